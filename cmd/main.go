@@ -1,27 +1,97 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	params "github.com/elribeiro/s3-stats-tool/internal/params"
 	"github.com/elribeiro/s3-stats-tool/internal/report"
 	"github.com/elribeiro/s3-stats-tool/internal/s3stats"
+	"github.com/elribeiro/s3-stats-tool/package/s3client"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
 	params := params.ParamsInput()
 
-	s3s := s3stats.NewS3Stats()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
+	s3s, err := s3stats.NewS3Stats(ctx, s3client.ClientConfig{
+		Endpoint:             params.Endpoint,
+		UsePathStyle:         params.UsePathStyle,
+		Region:               params.Region,
+		CABundle:             params.CABundle,
+		InsecureSkipVerify:   params.InsecureSkipVerify,
+		Profile:              params.Profile,
+		RoleARN:              params.RoleARN,
+		ExternalID:           params.ExternalID,
+		RoleSessionName:      params.RoleSessionName,
+		WebIdentityTokenFile: params.WebIdentityTokenFile,
+	})
+	if err != nil {
+		log.Fatal("Error while creating S3 Client: ", err)
+	}
 
-	bs, err := s3s.GenerateBucketStats(&s3stats.GenerateBucketStatsInput{
-		GetReplicationRules: params.GetReplicationRules,
-		GetLifecycleRules:   params.GetLifecycleRules,
-		NumberOfThreads:     params.NumberOfThreads,
-		FilterObjectPrefix:  params.FilterObjectPrefix,
-		FilterBucketName:    params.FilterBucketName})
+	bs, err := s3s.GenerateBucketStats(ctx, &s3stats.GenerateBucketStatsInput{
+		GetReplicationRules:    params.GetReplicationRules,
+		GetLifecycleRules:      params.GetLifecycleRules,
+		GetVersionStats:        params.GetVersionStats,
+		GetMultipartUploads:    params.GetMultipartUploads,
+		GetVersioningInfo:      params.GetVersioningInfo,
+		GetEncryptionInfo:      params.GetEncryptionInfo,
+		GetPublicAccessInfo:    params.GetPublicAccessInfo,
+		NumberOfThreads:        params.NumberOfThreads,
+		FilterObjectPrefixes:   params.FilterObjectPrefixes,
+		ModifiedAfter:          params.ModifiedAfter,
+		ModifiedBefore:         params.ModifiedBefore,
+		MinSize:                params.MinSize,
+		MaxSize:                params.MaxSize,
+		KeySuffixes:            params.KeySuffixes,
+		KeyPattern:             params.KeyPattern,
+		StorageClasses:         params.StorageClasses,
+		FilterBucketName:       params.FilterBucketName,
+		BucketNames:            params.BucketNames,
+		IncludeBuckets:         params.IncludeBuckets,
+		ExcludeBuckets:         params.ExcludeBuckets,
+		CreatedAfter:           params.CreatedAfter,
+		CreatedBefore:          params.CreatedBefore,
+		IncludeRegions:         params.IncludeRegions,
+		ExcludeRegions:         params.ExcludeRegions,
+		SizeDistributionBounds: params.SizeDistributionBounds,
+		PrefixDepth:            params.PrefixDepth,
+		PrefixDelimiter:        params.PrefixDelimiter,
+		TopN:                   params.TopN,
+		ShardMode:              params.ShardMode,
+		ShardBoundaries:        params.ShardBoundaries,
+		BucketTimeout:          params.BucketTimeout,
+		CheckpointFile:         params.CheckpointFile,
+		CheckpointInterval:     params.CheckpointInterval,
+		Resume:                 params.Resume})
 
 	if err != nil {
 		log.Fatal("Error: ", err)
 	}
 
 	report.OutputData(&report.Report{BucketStats: bs, WriteToFile: params.WriteToFile})
+
+	if bs.Incomplete {
+		log.Warnf("Job interrupted, %v buckets were skipped and the report is incomplete", bs.Summary.SkippedBuckets)
+	}
+
+	if bs.Summary.FailedBuckets > 0 {
+		log.Warnf("Stats of %v out of %v buckets could not be collected: %v",
+			bs.Summary.FailedBuckets, bs.Summary.TotalBuckets, bs.Summary.FailuresByCategory)
+	}
+
+	if params.FailOnError && (bs.Summary.FailedBuckets > 0 || bs.Incomplete) {
+		os.Exit(1)
+	}
 }
@@ -5,6 +5,9 @@ go 1.16
 require (
 	github.com/aws/aws-sdk-go-v2 v1.3.1
 	github.com/aws/aws-sdk-go-v2/config v1.1.3
+	github.com/aws/aws-sdk-go-v2/credentials v1.1.3
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.4.0
+	github.com/aws/aws-sdk-go-v2/service/sts v1.2.0
+	github.com/aws/smithy-go v1.3.0
 	github.com/sirupsen/logrus v1.8.1
 )
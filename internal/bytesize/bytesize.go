@@ -0,0 +1,78 @@
+package bytesize
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var units = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+// Format formats a size in the largest unit it reaches, without decimals when it
+// is a whole number of that unit, e.g. 5 GiB or 1.50 KiB.
+func Format(sizeInBytes int64) string {
+	s, i := scale(sizeInBytes)
+	if s == math.Trunc(s) {
+		return fmt.Sprintf("%.0f %v", s, units[i])
+	}
+	return fmt.Sprintf("%.2f %v", s, units[i])
+}
+
+// FormatFixed formats a size as Format does but always with two decimals above
+// bytes, e.g. 5.00 GiB, as the human readable sizes of the report.
+func FormatFixed(sizeInBytes int64) string {
+	s, i := scale(sizeInBytes)
+	if i == 0 {
+		return fmt.Sprintf("%d %v", sizeInBytes, units[i])
+	}
+	return fmt.Sprintf("%.2f %v", s, units[i])
+}
+
+func scale(sizeInBytes int64) (float64, int) {
+	s := float64(sizeInBytes)
+	i := 0
+	for math.Abs(s) >= 1024 && i < len(units)-1 {
+		s /= 1024
+		i++
+	}
+	return s, i
+}
+
+func Parse(size string) (int64, error) {
+	s := strings.TrimSpace(size)
+	if s == "" {
+		return 0, errors.New("Size is required")
+	}
+
+	for i := len(units) - 1; i >= 0; i-- {
+		u := units[i]
+		if !strings.HasSuffix(strings.ToLower(s), strings.ToLower(u)) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-len(u)]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid size %v", size)
+		}
+		return int64(v * math.Pow(1024, float64(i))), nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid size %v", size)
+	}
+	return v, nil
+}
+
+func ParseList(sizes string) ([]int64, error) {
+	var r []int64
+	for _, s := range strings.Split(sizes, ",") {
+		v, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
@@ -0,0 +1,82 @@
+package bytesize_test
+
+import (
+	"testing"
+
+	"github.com/elribeiro/s3-stats-tool/internal/bytesize"
+)
+
+func TestFormat(t *testing.T) {
+	result := []struct {
+		size int64
+		want string
+	}{
+		{size: 512, want: "512 B"},
+		{size: 1536, want: "1.50 KiB"},
+		{size: 5 * 1024 * 1024 * 1024, want: "5 GiB"},
+	}
+
+	for _, r := range result {
+		if got := bytesize.Format(r.size); got != r.want {
+			t.Errorf("Expecting %v, got %v", r.want, got)
+		}
+	}
+}
+
+func TestFormatFixed(t *testing.T) {
+	result := []struct {
+		size int64
+		want string
+	}{
+		{size: 512, want: "512 B"},
+		{size: 1536, want: "1.50 KiB"},
+		{size: 5 * 1024 * 1024 * 1024, want: "5.00 GiB"},
+	}
+
+	for _, r := range result {
+		if got := bytesize.FormatFixed(r.size); got != r.want {
+			t.Errorf("Expecting %v, got %v", r.want, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	result := []struct {
+		size string
+		want int64
+	}{
+		{size: "512", want: 512},
+		{size: "512B", want: 512},
+		{size: "128KiB", want: 128 * 1024},
+		{size: "1.5 mib", want: 1536 * 1024},
+		{size: "5GiB", want: 5 * 1024 * 1024 * 1024},
+	}
+
+	for _, r := range result {
+		got, err := bytesize.Parse(r.size)
+		if err != nil {
+			t.Errorf("Expecting no error, got %v", err)
+		}
+		if got != r.want {
+			t.Errorf("Expecting %v, got %v", r.want, got)
+		}
+	}
+
+	_, err := bytesize.Parse("10XB")
+	if err == nil {
+		t.Errorf("Expecting error, got nil")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	r, err := bytesize.ParseList("1KiB,128KiB,1MiB")
+	if err != nil {
+		t.Errorf("Expecting no error, got %v", err)
+	}
+	if len(r) != 3 {
+		t.Fatalf("Expecting %v, got %v", 3, len(r))
+	}
+	if r[1] != 128*1024 {
+		t.Errorf("Expecting %v, got %v", 128*1024, r[1])
+	}
+}
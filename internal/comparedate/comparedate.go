@@ -8,3 +8,14 @@ func GetMostRecentDate(cur, new *time.Time) *time.Time {
 	}
 	return cur
 }
+
+func GetOldestDate(cur, new *time.Time) *time.Time {
+	if new.Before(*cur) {
+		return new
+	}
+	return cur
+}
+
+func GetAgeInDays(ref, date *time.Time) int {
+	return int(ref.Sub(*date).Hours() / 24)
+}
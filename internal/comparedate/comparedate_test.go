@@ -24,3 +24,33 @@ func TestGetMostRecentDate(t *testing.T) {
 	}
 
 }
+
+func TestGetOldestDate(t *testing.T) {
+	cur := time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC)
+	new := time.Date(2020, time.April, 10, 22, 40, 20, 23, time.UTC)
+
+	r := comparedate.GetOldestDate(&cur, &new)
+
+	if !r.Equal(cur) {
+		t.Errorf("Expecting %v, got %v", cur, r)
+	}
+
+	r = comparedate.GetOldestDate(&new, &cur)
+
+	if !r.Equal(cur) {
+		t.Errorf("Expecting %v, got %v", cur, r)
+	}
+
+}
+
+func TestGetAgeInDays(t *testing.T) {
+	ref := time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC)
+	date := time.Date(2020, time.March, 10, 23, 40, 20, 22, time.UTC)
+
+	r := comparedate.GetAgeInDays(&ref, &date)
+
+	if r != 30 {
+		t.Errorf("Expecting %v, got %v", 30, r)
+	}
+
+}
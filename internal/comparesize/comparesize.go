@@ -0,0 +1,15 @@
+package comparesize
+
+func GetLargestSize(cur, new *int64) *int64 {
+	if *new > *cur {
+		return new
+	}
+	return cur
+}
+
+func GetSmallestSize(cur, new *int64) *int64 {
+	if *new < *cur {
+		return new
+	}
+	return cur
+}
@@ -0,0 +1,43 @@
+package comparesize_test
+
+import (
+	"testing"
+
+	"github.com/elribeiro/s3-stats-tool/internal/comparesize"
+)
+
+func TestGetLargestSize(t *testing.T) {
+	var cur int64 = 1024
+	var new int64 = 2048
+
+	r := comparesize.GetLargestSize(&cur, &new)
+
+	if *r != new {
+		t.Errorf("Expecting %v, got %v", new, *r)
+	}
+
+	r = comparesize.GetLargestSize(&new, &cur)
+
+	if *r != new {
+		t.Errorf("Expecting %v, got %v", new, *r)
+	}
+
+}
+
+func TestGetSmallestSize(t *testing.T) {
+	var cur int64 = 1024
+	var new int64 = 2048
+
+	r := comparesize.GetSmallestSize(&cur, &new)
+
+	if *r != cur {
+		t.Errorf("Expecting %v, got %v", cur, *r)
+	}
+
+	r = comparesize.GetSmallestSize(&new, &cur)
+
+	if *r != cur {
+		t.Errorf("Expecting %v, got %v", cur, *r)
+	}
+
+}
@@ -1,22 +1,79 @@
 package params
 
 import (
+	"bufio"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elribeiro/s3-stats-tool/internal/bytesize"
+	"github.com/elribeiro/s3-stats-tool/package/s3client"
 )
 
 type Params struct {
-	GetReplicationRules bool
-	GetLifecycleRules   bool
-	FilterObjectPrefix  string
-	FilterBucketName    string
-	NumberOfThreads     int
-	WriteToFile         bool
+	GetReplicationRules    bool
+	GetLifecycleRules      bool
+	GetVersionStats        bool
+	GetMultipartUploads    bool
+	GetVersioningInfo      bool
+	GetEncryptionInfo      bool
+	GetPublicAccessInfo    bool
+	FilterObjectPrefixes   []string
+	ModifiedAfter          time.Time
+	ModifiedBefore         time.Time
+	MinSize                int64
+	MaxSize                int64
+	KeySuffixes            []string
+	KeyPattern             string
+	StorageClasses         []string
+	FilterBucketName       string
+	BucketNames            []string
+	IncludeBuckets         []string
+	ExcludeBuckets         []string
+	CreatedAfter           time.Time
+	CreatedBefore          time.Time
+	IncludeRegions         []string
+	ExcludeRegions         []string
+	NumberOfThreads        int
+	WriteToFile            bool
+	FailOnError            bool
+	SizeDistributionBounds []int64
+	PrefixDepth            int
+	PrefixDelimiter        string
+	TopN                   int
+	ShardMode              string
+	ShardBoundaries        []string
+	Timeout                time.Duration
+	BucketTimeout          time.Duration
+	CheckpointFile         string
+	CheckpointInterval     time.Duration
+	Resume                 bool
+	Endpoint               string
+	UsePathStyle           bool
+	Region                 string
+	CABundle               string
+	InsecureSkipVerify     bool
+	Profile                string
+	RoleARN                string
+	ExternalID             string
+	RoleSessionName        string
+	WebIdentityTokenFile   string
 }
 
+// defaultCheckpointFile is resumed by -resume when -cp is not set.
+const defaultCheckpointFile = "s3stats-checkpoint.json"
+
 const (
 	numberOfThreadsMsg = `
 		Integer to define the number of threads to run concurrently.
-		Each thread will process one bucket at a time 
+		Each thread will process one bucket at a time, unless -sm is set, 
+		when idle threads help to list the shards of the buckets in progress
 		WATCH OUT: setting a high number may impact in high cost and computing resources usage
 		`
 
@@ -26,25 +83,268 @@ const (
 	`
 
 	getLifecycleRulesMsg = `
-		Boolean to define if this job will collect lifecycle rules as well
+		Boolean to define if this job will collect lifecycle rules as well. The size
+		bounds of rule filters are not reported, the S3 SDK in use does not return them
+		 (default false)
+	`
+
+	getVersionStatsMsg = `
+		Boolean to define if this job will collect object version stats as well,
+		reporting current and noncurrent versions and delete markers
+		 (default false)
+	`
+
+	getMultipartUploadsMsg = `
+		Boolean to define if this job will collect incomplete multipart uploads as well,
+		reporting uploaded parts size and if an abort incomplete uploads lifecycle rule exists
+		 (default false)
+	`
+
+	getVersioningInfoMsg = `
+		Boolean to define if this job will collect versioning, MFA delete and 
+		object lock configuration as well
+		 (default false)
+	`
+
+	getEncryptionInfoMsg = `
+		Boolean to define if this job will collect default encryption configuration as well
+		 (default false)
+	`
+
+	getPublicAccessInfoMsg = `
+		Boolean to define if this job will collect public access block, bucket policy and ACL
+		to assess the public exposure of each bucket as well, as blocked, policy-public,
+		acl-public, not-public or unknown
 		 (default false)
 	`
 
 	filterPrefixMsg = `
-		String to filter only objects that has a specific prefix
+		String to filter only objects that has a specific prefix. Can be repeated to
+		collect the objects under any of them
 		 (default no filter) 
 	`
 
+	modifiedAfterMsg = `
+		Date to filter only objects modified on or after it, as 2006-01-02 or RFC 3339
+		 (default no filter)
+	`
+
+	modifiedBeforeMsg = `
+		Date to filter only objects modified before it, as 2006-01-02 or RFC 3339
+		 (default no filter)
+	`
+
+	minSizeMsg = `
+		Size to filter only objects of at least this size, e.g. 1MiB.
+		Accepts B, KiB, MiB, GiB, TiB and PiB units
+		 (default no filter)
+	`
+
+	maxSizeMsg = `
+		Size to filter only objects of at most this size, e.g. 5GiB.
+		Accepts B, KiB, MiB, GiB, TiB and PiB units
+		 (default no filter)
+	`
+
+	keySuffixMsg = `
+		String to filter only objects whose key ends with it, e.g. .parquet. 
+		Can be repeated to match any of them
+		 (default no filter)
+	`
+
+	keyRegexMsg = `
+		Regular expression to filter only objects whose key matches it, e.g. \.parquet$
+		 (default no filter)
+	`
+
+	storageClassesMsg = `
+		Comma separated list of storage classes to filter only objects stored in them,
+		e.g. STANDARD,GLACIER
+		 (default all storage classes)
+	`
+
 	filterBucketNameMsg = `
 		String to filter only buckets that contains the specified value
 		 (default no filter)
 	`
 
+	includeBucketsMsg = `
+		Pattern to filter only buckets whose name matches it. Can be repeated to match any of them.
+		Accepts globs, e.g. prod-*, or regular expressions prefixed with regex:, e.g. regex:^prod-(a|b)-
+		 (default no filter)
+	`
+
+	excludeBucketsMsg = `
+		Pattern to filter out buckets whose name matches it, e.g. *-logs. Can be repeated.
+		Accepts the same patterns of -ib
+		 (default no filter)
+	`
+
+	bucketListMsg = `
+		String to define a file with the names of the buckets to collect, one per line.
+		Use - to read them from stdin. Blank lines and lines starting with # are ignored
+		 (default all buckets)
+	`
+
+	createdAfterMsg = `
+		Date to filter only buckets created on or after it, as 2006-01-02 or RFC 3339
+		 (default no filter)
+	`
+
+	createdBeforeMsg = `
+		Date to filter only buckets created before it, as 2006-01-02 or RFC 3339
+		 (default no filter)
+	`
+
+	includeRegionsMsg = `
+		Comma separated list of regions to filter only buckets located in them.
+		Accepts glob patterns, e.g. eu-*,us-east-1
+		 (default all regions)
+	`
+
+	excludeRegionsMsg = `
+		Comma separated list of regions to filter out buckets located in them.
+		Accepts glob patterns, e.g. eu-* to report everything stored outside the EU
+		 (default no region excluded)
+	`
+
 	writeToFileMsg = `
 		Bool to indicate if output will be to a file named st3stats-date.json,
 		where date is the current date. If not set, will output to console in json format
 		 (default false)
 		`
+
+	failOnErrorMsg = `
+		Bool to indicate if the job will exit with a non-zero code when stats
+		of any bucket could not be collected. The report is written either way
+		 (default false)
+	`
+
+	timeoutMsg = `
+		Duration to define the deadline of the whole job, e.g. 30m or 2h. When reached,
+		no more buckets are processed and the stats collected so far are reported as incomplete
+		 (default 0, no deadline)
+	`
+
+	bucketTimeoutMsg = `
+		Duration to define the deadline to collect the stats of a single bucket, e.g. 10m.
+		Buckets that exceed it are reported with a timeout error
+		 (default 0, no deadline)
+	`
+
+	checkpointFileMsg = `
+		String to define the file where the progress is saved to be resumed with -resume.
+		The file is removed when every bucket is collected. May hold bucket policies when -pa is set
+		 (default no checkpoint, or s3stats-checkpoint.json when -resume is set)
+	`
+
+	checkpointIntervalMsg = `
+		Duration to define how often the progress is saved to the -cp file, 
+		besides when the job ends with skipped or failed buckets
+	`
+
+	resumeMsg = `
+		Bool to indicate if the job will resume from the -cp file, skipping completed buckets
+		and listing in progress buckets from the last key listed. Requires the same options
+		of the job that saved it
+		 (default false)
+	`
+
+	endpointMsg = `
+		URL of an S3 compatible service to connect to instead of AWS, 
+		e.g. https://minio.local:9000 for MinIO, Ceph RGW or localstack
+		 (default AWS endpoints)
+	`
+
+	pathStyleMsg = `
+		Bool to indicate if buckets are addressed in the path of the URL instead of 
+		in the hostname, as most S3 compatible services require
+		 (default false)
+	`
+
+	regionMsg = `
+		String to override the region of the AWS configuration. Buckets whose location 
+		cannot be looked up, as on services without GetBucketLocation, are accessed in it
+		 (default region of the AWS configuration)
+	`
+
+	caBundleMsg = `
+		String to define a PEM file with certificates to trust besides those of the system,
+		e.g. the internal CA of an on-premises service
+		 (default system certificates)
+	`
+
+	insecureMsg = `
+		Bool to indicate if the certificate of the service is not verified.
+		WATCH OUT: use only to test services with self signed certificates
+		 (default false)
+	`
+
+	profileMsg = `
+		String to define the profile of the AWS shared config and credentials files to use
+		 (default AWS_PROFILE or the default profile)
+	`
+
+	roleMsg = `
+		ARN of a role to assume with the credentials of the profile, or with the token 
+		of -wit when it is set, e.g. to collect the stats of another account
+		 (default no role)
+	`
+
+	externalIDMsg = `
+		String to define the external ID required by the trust policy of the -role.
+		Cannot be set with -wit, roles assumed with a web identity take no external ID
+		 (default no external ID)
+	`
+
+	roleSessionNameMsg = `
+		String to define the session name of the -role, as shown in CloudTrail
+		 (default generated name)
+	`
+
+	webIdentityTokenFileMsg = `
+		String to define a file with an OIDC token exchanged for the credentials of 
+		the -role, e.g. the token of an EKS service account
+		 (default no token)
+	`
+
+	sizeDistributionMsg = `
+		Comma separated list of upper bounds used to build the object size histogram, 
+		e.g. 1KiB,128KiB,1MiB,100MiB,5GiB. Accepts B, KiB, MiB, GiB, TiB and PiB units
+		 (default 1KiB,128KiB,1MiB,100MiB,5GiB)
+	`
+
+	prefixDepthMsg = `
+		Integer to define how many prefix levels each bucket will be broken down into.
+		Each level reports total files, size and most recent modified date. Levels are split
+		from the keys of the single listing of the bucket, so no extra request is made
+		 (default 0, no breakdown)
+	`
+
+	prefixDelimiterMsg = `
+		String used as delimiter between prefix levels when -pd is set
+	`
+
+	shardModeMsg = `
+		String to define how a bucket is split to be listed in parallel by idle threads.
+		Use prefix to list each prefix found below -fo with the -pdl delimiter in parallel,
+		or range to list each range of keys between the -sb boundaries in parallel
+		 (default no split)
+	`
+
+	shardBoundariesMsg = `
+		Comma separated list of keys, relative to -fo, where the ranges listed in 
+		parallel are split when -sm is range, e.g. 2020,2021,2022
+		 (default 0,1,...,9,a,b,...,z)
+	`
+
+	topNMsg = `
+		Integer to define how many of the largest objects will be reported per bucket.
+		When -pd is set, the same number of largest prefixes will be reported as well.
+		Only the deepest prefix of each branch is ranked, as a parent counts the bytes of
+		its children; objects directly in a prefix that has child prefixes are not ranked
+		 (default 0, disabled)
+	`
 )
 
 func ParamsInput() *Params {
@@ -52,18 +352,236 @@ func ParamsInput() *Params {
 	numberOfThreads := flag.Int("t", 2, numberOfThreadsMsg)
 	getReplicationRules := flag.Bool("r", false, getReplicationRulesMsg)
 	getLifecycleRules := flag.Bool("l", false, getLifecycleRulesMsg)
-	filterObjectPrefix := flag.String("fo", "", filterPrefixMsg)
+	getVersionStats := flag.Bool("v", false, getVersionStatsMsg)
+	getMultipartUploads := flag.Bool("mu", false, getMultipartUploadsMsg)
+	getVersioningInfo := flag.Bool("vi", false, getVersioningInfoMsg)
+	getEncryptionInfo := flag.Bool("e", false, getEncryptionInfoMsg)
+	getPublicAccessInfo := flag.Bool("pa", false, getPublicAccessInfoMsg)
 	filterBucketName := flag.String("fb", "", filterBucketNameMsg)
 	writeToFile := flag.Bool("o", false, writeToFileMsg)
+	failOnError := flag.Bool("fe", false, failOnErrorMsg)
+	prefixDepth := flag.Int("pd", 0, prefixDepthMsg)
+	prefixDelimiter := flag.String("pdl", "/", prefixDelimiterMsg)
+	topN := flag.Int("top", 0, topNMsg)
+	timeout := flag.Duration("timeout", 0, timeoutMsg)
+	bucketTimeout := flag.Duration("bt", 0, bucketTimeoutMsg)
+	checkpointFile := flag.String("cp", "", checkpointFileMsg)
+	checkpointInterval := flag.Duration("ci", time.Minute, checkpointIntervalMsg)
+	resume := flag.Bool("resume", false, resumeMsg)
+	endpoint := flag.String("endpoint", "", endpointMsg)
+	usePathStyle := flag.Bool("ps", false, pathStyleMsg)
+	region := flag.String("region", "", regionMsg)
+	caBundle := flag.String("cab", "", caBundleMsg)
+	insecure := flag.Bool("insecure", false, insecureMsg)
+	profile := flag.String("profile", "", profileMsg)
+	roleARN := flag.String("role", "", roleMsg)
+	externalID := flag.String("eid", "", externalIDMsg)
+	roleSessionName := flag.String("rsn", "", roleSessionNameMsg)
+	webIdentityTokenFile := flag.String("wit", "", webIdentityTokenFileMsg)
+	var shardMode string
+	flag.Func("sm", shardModeMsg, func(v string) error {
+		if v != s3client.ShardPrefix && v != s3client.ShardRange {
+			return fmt.Errorf("must be %v or %v", s3client.ShardPrefix, s3client.ShardRange)
+		}
+		shardMode = v
+		return nil
+	})
+	var shardBoundaries []string
+	flag.Func("sb", shardBoundariesMsg, func(v string) error {
+		shardBoundaries = strings.Split(v, ",")
+		return nil
+	})
+	var includeBuckets []string
+	flag.Func("ib", includeBucketsMsg, func(v string) error {
+		_, err := s3client.ParseNamePattern(v)
+		includeBuckets = append(includeBuckets, v)
+		return err
+	})
+	var excludeBuckets []string
+	flag.Func("eb", excludeBucketsMsg, func(v string) error {
+		_, err := s3client.ParseNamePattern(v)
+		excludeBuckets = append(excludeBuckets, v)
+		return err
+	})
+	var bucketNames []string
+	flag.Func("bl", bucketListMsg, func(v string) error {
+		n, err := readBucketList(v)
+		bucketNames = n
+		return err
+	})
+	var createdAfter, createdBefore time.Time
+	flag.Func("ca", createdAfterMsg, func(v string) error {
+		d, err := parseDate(v)
+		createdAfter = d
+		return err
+	})
+	flag.Func("cb", createdBeforeMsg, func(v string) error {
+		d, err := parseDate(v)
+		createdBefore = d
+		return err
+	})
+	var includeRegions []string
+	flag.Func("ir", includeRegionsMsg, func(v string) error {
+		r, err := parseRegions(v)
+		includeRegions = r
+		return err
+	})
+	var excludeRegions []string
+	flag.Func("er", excludeRegionsMsg, func(v string) error {
+		r, err := parseRegions(v)
+		excludeRegions = r
+		return err
+	})
+	var filterObjectPrefixes []string
+	flag.Func("fo", filterPrefixMsg, func(v string) error {
+		filterObjectPrefixes = append(filterObjectPrefixes, v)
+		return nil
+	})
+	var modifiedAfter, modifiedBefore time.Time
+	flag.Func("ma", modifiedAfterMsg, func(v string) error {
+		d, err := parseDate(v)
+		modifiedAfter = d
+		return err
+	})
+	flag.Func("mb", modifiedBeforeMsg, func(v string) error {
+		d, err := parseDate(v)
+		modifiedBefore = d
+		return err
+	})
+	var minSize, maxSize int64
+	flag.Func("min", minSizeMsg, func(v string) error {
+		s, err := bytesize.Parse(v)
+		minSize = s
+		return err
+	})
+	flag.Func("max", maxSizeMsg, func(v string) error {
+		s, err := bytesize.Parse(v)
+		maxSize = s
+		return err
+	})
+	var keySuffixes []string
+	flag.Func("ks", keySuffixMsg, func(v string) error {
+		keySuffixes = append(keySuffixes, v)
+		return nil
+	})
+	var keyPattern string
+	flag.Func("kr", keyRegexMsg, func(v string) error {
+		_, err := regexp.Compile(v)
+		keyPattern = v
+		return err
+	})
+	var storageClasses []string
+	flag.Func("sc", storageClassesMsg, func(v string) error {
+		storageClasses = strings.Split(strings.ToUpper(v), ",")
+		return nil
+	})
+	var sizeDistributionBounds []int64
+	flag.Func("sd", sizeDistributionMsg, func(v string) error {
+		b, err := bytesize.ParseList(v)
+		sizeDistributionBounds = b
+		return err
+	})
 
 	flag.Parse()
 
+	if *resume && *checkpointFile == "" {
+		*checkpointFile = defaultCheckpointFile
+	}
+
 	return &Params{
-		GetReplicationRules: *getReplicationRules,
-		GetLifecycleRules:   *getLifecycleRules,
-		FilterObjectPrefix:  *filterObjectPrefix,
-		FilterBucketName:    *filterBucketName,
-		NumberOfThreads:     *numberOfThreads,
-		WriteToFile:         *writeToFile,
+		GetReplicationRules:    *getReplicationRules,
+		GetLifecycleRules:      *getLifecycleRules,
+		GetVersionStats:        *getVersionStats,
+		GetMultipartUploads:    *getMultipartUploads,
+		GetVersioningInfo:      *getVersioningInfo,
+		GetEncryptionInfo:      *getEncryptionInfo,
+		GetPublicAccessInfo:    *getPublicAccessInfo,
+		FilterObjectPrefixes:   filterObjectPrefixes,
+		ModifiedAfter:          modifiedAfter,
+		ModifiedBefore:         modifiedBefore,
+		MinSize:                minSize,
+		MaxSize:                maxSize,
+		KeySuffixes:            keySuffixes,
+		KeyPattern:             keyPattern,
+		StorageClasses:         storageClasses,
+		FilterBucketName:       *filterBucketName,
+		BucketNames:            bucketNames,
+		IncludeBuckets:         includeBuckets,
+		ExcludeBuckets:         excludeBuckets,
+		CreatedAfter:           createdAfter,
+		CreatedBefore:          createdBefore,
+		IncludeRegions:         includeRegions,
+		ExcludeRegions:         excludeRegions,
+		NumberOfThreads:        *numberOfThreads,
+		WriteToFile:            *writeToFile,
+		FailOnError:            *failOnError,
+		SizeDistributionBounds: sizeDistributionBounds,
+		PrefixDepth:            *prefixDepth,
+		PrefixDelimiter:        *prefixDelimiter,
+		TopN:                   *topN,
+		ShardMode:              shardMode,
+		ShardBoundaries:        shardBoundaries,
+		Timeout:                *timeout,
+		BucketTimeout:          *bucketTimeout,
+		CheckpointFile:         *checkpointFile,
+		CheckpointInterval:     *checkpointInterval,
+		Resume:                 *resume,
+		Endpoint:               *endpoint,
+		UsePathStyle:           *usePathStyle,
+		Region:                 *region,
+		CABundle:               *caBundle,
+		InsecureSkipVerify:     *insecure,
+		Profile:                *profile,
+		RoleARN:                *roleARN,
+		ExternalID:             *externalID,
+		RoleSessionName:        *roleSessionName,
+		WebIdentityTokenFile:   *webIdentityTokenFile,
+	}
+}
+
+func parseRegions(v string) ([]string, error) {
+	r := strings.Split(v, ",")
+	for _, p := range r {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return r, nil
+}
+
+// readBucketList reads one bucket name per line from the file, or stdin for -.
+func readBucketList(file string) ([]string, error) {
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		n := strings.TrimSpace(sc.Text())
+		if n == "" || strings.HasPrefix(n, "#") {
+			continue
+		}
+		names = append(names, n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no bucket names found")
+	}
+	return names, nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	if d, err := time.Parse("2006-01-02", v); err == nil {
+		return d, nil
 	}
+	return time.Parse(time.RFC3339, v)
 }
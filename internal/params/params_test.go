@@ -1,6 +1,7 @@
 package params_test
 
 import (
+	"strings"
 	"testing"
 
 	params "github.com/elribeiro/s3-stats-tool/internal/params"
@@ -47,8 +48,8 @@ func TestParamsInput(t *testing.T) {
 		t.Errorf("Expecting %v, got %v", result.wantWriteToFile, params.WriteToFile)
 	}
 
-	if params.FilterObjectPrefix != result.wantObjectFilter {
-		t.Errorf("Expecting %v, got %v", result.wantObjectFilter, params.FilterObjectPrefix)
+	if strings.Join(params.FilterObjectPrefixes, "") != result.wantObjectFilter {
+		t.Errorf("Expecting %v, got %v", result.wantObjectFilter, params.FilterObjectPrefixes)
 	}
 
 	if params.FilterBucketName != result.wantBucketFilter {
@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type document struct {
+	Statement statements
+}
+
+type statement struct {
+	Sid       string
+	Effect    string
+	Principal interface{}
+	Condition map[string]interface{}
+}
+
+// statements accepts both a single statement object and a list of statements,
+// as allowed by the IAM policy grammar.
+type statements []statement
+
+func (s *statements) UnmarshalJSON(b []byte) error {
+	var l []statement
+	if err := json.Unmarshal(b, &l); err == nil {
+		*s = l
+		return nil
+	}
+
+	var st statement
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	*s = statements{st}
+	return nil
+}
+
+// PublicStatements returns the Sid (or index, when Sid is not set) of every Allow
+// statement granted to any principal ("*") without conditions.
+func PublicStatements(policy string) ([]string, error) {
+	var d document
+	if err := json.Unmarshal([]byte(policy), &d); err != nil {
+		return nil, err
+	}
+
+	var r []string
+	for i, st := range d.Statement {
+		if st.Effect != "Allow" || len(st.Condition) > 0 || !isAnyPrincipal(st.Principal) {
+			continue
+		}
+		id := st.Sid
+		if id == "" {
+			id = fmt.Sprintf("%v", i)
+		}
+		r = append(r, id)
+	}
+
+	return r, nil
+}
+
+func isAnyPrincipal(p interface{}) bool {
+	switch v := p.(type) {
+	case string:
+		return v == "*"
+	case []interface{}:
+		for _, i := range v {
+			if isAnyPrincipal(i) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, i := range v {
+			if isAnyPrincipal(i) {
+				return true
+			}
+		}
+	}
+	return false
+}
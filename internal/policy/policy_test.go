@@ -0,0 +1,53 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/elribeiro/s3-stats-tool/internal/policy"
+)
+
+func TestPublicStatements(t *testing.T) {
+	result := []struct {
+		policy   string
+		wantSize int
+		wantID   string
+	}{
+		{
+			policy:   `{"Statement":{"Sid":"public","Effect":"Allow","Principal":"*","Action":"s3:GetObject"}}`,
+			wantSize: 1,
+			wantID:   "public",
+		},
+		{
+			policy: `{"Statement":[
+				{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789123:root","*"]},"Action":"s3:GetObject"},
+				{"Sid":"vpc","Effect":"Allow","Principal":"*","Action":"s3:GetObject","Condition":{"StringEquals":{"aws:SourceVpce":"vpce-1"}}},
+				{"Sid":"deny","Effect":"Deny","Principal":"*","Action":"s3:*"}
+			]}`,
+			wantSize: 1,
+			wantID:   "0",
+		},
+		{
+			policy:   `{"Statement":[{"Sid":"account","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789123:root"},"Action":"s3:*"}]}`,
+			wantSize: 0,
+		},
+	}
+
+	for _, r := range result {
+		ps, err := policy.PublicStatements(r.policy)
+		if err != nil {
+			t.Errorf("Expecting no error, got %v", err)
+		}
+		if len(ps) != r.wantSize {
+			t.Errorf("Expecting %v, got %v", r.wantSize, len(ps))
+			continue
+		}
+		if r.wantSize > 0 && ps[0] != r.wantID {
+			t.Errorf("Expecting %v, got %v", r.wantID, ps[0])
+		}
+	}
+
+	_, err := policy.PublicStatements("not a policy")
+	if err == nil {
+		t.Errorf("Expecting error, got nil")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/elribeiro/s3-stats-tool/internal/bytesize"
 	"github.com/elribeiro/s3-stats-tool/internal/s3stats"
 	log "github.com/sirupsen/logrus"
 )
@@ -17,7 +18,21 @@ type Report struct {
 }
 
 func OutputData(params *Report) {
-	jsonString, err := json.MarshalIndent(params.BucketStats, "", " ")
+	out := s3stats.GenerateBucketStatsOutput{
+		Summary:    params.BucketStats.Summary,
+		Filters:    params.BucketStats.Filters,
+		Incomplete: params.BucketStats.Incomplete,
+	}
+	for _, bs := range params.BucketStats.BucketsStats {
+		bs.Size = SizeInUnits(bs.SizeInBytes)
+		out.BucketsStats = append(out.BucketsStats, bs)
+	}
+	for _, rs := range params.BucketStats.Regions {
+		rs.Size = SizeInUnits(rs.SizeInBytes)
+		out.Regions = append(out.Regions, rs)
+	}
+
+	jsonString, err := json.MarshalIndent(out, "", " ")
 	if err != nil {
 		log.Fatal("Error while marshalling input data, details: ", err)
 	}
@@ -29,3 +44,19 @@ func OutputData(params *Report) {
 		fmt.Println(string(jsonString))
 	}
 }
+
+func SizeInUnits(sizeInBytes int64) s3stats.SizeUnits {
+	s := float64(sizeInBytes)
+
+	return s3stats.SizeUnits{
+		KiB:   s / (1 << 10),
+		MiB:   s / (1 << 20),
+		GiB:   s / (1 << 30),
+		TiB:   s / (1 << 40),
+		Human: HumanSize(sizeInBytes),
+	}
+}
+
+func HumanSize(sizeInBytes int64) string {
+	return bytesize.FormatFixed(sizeInBytes)
+}
@@ -52,3 +52,32 @@ func TestOutputData(t *testing.T) {
 		t.Errorf("Error while file cleanup, details: %v ", err)
 	}
 }
+
+func TestHumanSize(t *testing.T) {
+	result := []struct {
+		size int64
+		want string
+	}{
+		{size: 512, want: "512 B"},
+		{size: 1536, want: "1.50 KiB"},
+		{size: 5 * 1024 * 1024 * 1024, want: "5.00 GiB"},
+	}
+
+	for _, r := range result {
+		if got := report.HumanSize(r.size); got != r.want {
+			t.Errorf("Expecting %v, got %v", r.want, got)
+		}
+	}
+}
+
+func TestSizeInUnits(t *testing.T) {
+	s := report.SizeInUnits(3 * 1024 * 1024)
+
+	if s.MiB != 3 {
+		t.Errorf("Expecting %v, got %v", 3, s.MiB)
+	}
+
+	if s.KiB != 3072 {
+		t.Errorf("Expecting %v, got %v", 3072, s.KiB)
+	}
+}
@@ -0,0 +1,76 @@
+package s3stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/elribeiro/s3-stats-tool/package/s3client"
+)
+
+// Checkpoint is the progress of GenerateBucketStats persisted to a file, so a run
+// that was interrupted or had failed buckets can be resumed without listing the
+// finished work again.
+type Checkpoint struct {
+	Input      GenerateBucketStatsInput                `json:"input"`
+	Completed  []BucketStats                           `json:"completed"`
+	InProgress map[string]s3client.ObjectStatsProgress `json:"in_progress,omitempty"`
+}
+
+// checkpointInput keeps only the options that change the collected stats, which
+// must be the same for a run to be resumed from a checkpoint.
+func checkpointInput(params *GenerateBucketStatsInput) GenerateBucketStatsInput {
+	r := *params
+	r.NumberOfThreads = 0
+	r.BucketTimeout = 0
+	r.CheckpointFile = ""
+	r.CheckpointInterval = 0
+	r.Resume = false
+	return r
+}
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Save writes the checkpoint to a temporary file renamed over path, so a crash
+// while saving never leaves a truncated checkpoint behind.
+func (cp *Checkpoint) Save(path string) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// validate compares the options in their JSON form, the form they are saved in.
+func (cp *Checkpoint) validate(params *GenerateBucketStatsInput) error {
+	saved, err := json.Marshal(cp.Input)
+	if err != nil {
+		return err
+	}
+	current, err := json.Marshal(checkpointInput(params))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(saved, current) {
+		return errors.New("checkpoint was created with different options, run with the same options to resume")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package s3stats
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	ErrorAccessDenied = "access-denied"
+	ErrorNotFound     = "not-found"
+	ErrorThrottled    = "throttled"
+	ErrorTimeout      = "timeout"
+	ErrorCanceled     = "canceled"
+	ErrorOther        = "other"
+)
+
+type BucketError struct {
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+var errorCodes = map[string]string{
+	"AccessDenied":          ErrorAccessDenied,
+	"AllAccessDisabled":     ErrorAccessDenied,
+	"ExpiredToken":          ErrorAccessDenied,
+	"InvalidAccessKeyId":    ErrorAccessDenied,
+	"SignatureDoesNotMatch": ErrorAccessDenied,
+	"NoSuchBucket":          ErrorNotFound,
+	"NotFound":              ErrorNotFound,
+	"SlowDown":              ErrorThrottled,
+	"Throttling":            ErrorThrottled,
+	"RequestLimitExceeded":  ErrorThrottled,
+	"ServiceUnavailable":    ErrorThrottled,
+	"RequestTimeout":        ErrorTimeout,
+}
+
+// ErrorCategory classifies an error returned while collecting stats of a bucket.
+func ErrorCategory(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return ErrorTimeout
+	}
+
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		if c, ok := errorCodes[ae.ErrorCode()]; ok {
+			return c
+		}
+	}
+
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		switch re.Response.StatusCode {
+		case 403:
+			return ErrorAccessDenied
+		case 404:
+			return ErrorNotFound
+		case 429, 503:
+			return ErrorThrottled
+		}
+	}
+
+	return ErrorOther
+}
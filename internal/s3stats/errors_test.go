@@ -0,0 +1,39 @@
+package s3stats_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/elribeiro/s3-stats-tool/internal/s3stats"
+)
+
+func TestErrorCategory(t *testing.T) {
+	notFound := &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: 404}},
+		Err:      errors.New("not found"),
+	}}
+
+	result := []struct {
+		err  error
+		want string
+	}{
+		{err: &smithy.GenericAPIError{Code: "AccessDenied"}, want: s3stats.ErrorAccessDenied},
+		{err: &smithy.GenericAPIError{Code: "SlowDown"}, want: s3stats.ErrorThrottled},
+		{err: fmt.Errorf("wrapped: %w", notFound), want: s3stats.ErrorNotFound},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), want: s3stats.ErrorTimeout},
+		{err: context.Canceled, want: s3stats.ErrorCanceled},
+		{err: errors.New("anything else"), want: s3stats.ErrorOther},
+	}
+
+	for _, r := range result {
+		if got := s3stats.ErrorCategory(r.err); got != r.want {
+			t.Errorf("Expecting %v, got %v", r.want, got)
+		}
+	}
+}
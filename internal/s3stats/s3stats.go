@@ -2,6 +2,8 @@ package s3stats
 
 import (
 	"context"
+	"os"
+	"sort"
 	"sync"
 
 	"time"
@@ -20,6 +22,24 @@ type S3ClientApi interface {
 
 	GetBucketLifecycleInfo(c context.Context,
 		params *s3client.BucketLifeCycleInfoInput) (s3client.BucketLifeCycleInfoOutput, error)
+
+	GetObjectVersionStats(c context.Context,
+		params *s3client.ObjectVersionStatsInput) (s3client.ObjectVersionStatsOutput, error)
+
+	GetMultipartUploadStats(c context.Context,
+		params *s3client.MultipartUploadStatsInput) (s3client.MultipartUploadStatsOutput, error)
+
+	GetBucketVersioningInfo(c context.Context,
+		params *s3client.BucketVersioningInfoInput) (s3client.BucketVersioningInfoOutput, error)
+
+	GetBucketObjectLockInfo(c context.Context,
+		params *s3client.BucketObjectLockInfoInput) (s3client.BucketObjectLockInfoOutput, error)
+
+	GetBucketEncryptionInfo(c context.Context,
+		params *s3client.BucketEncryptionInfoInput) (s3client.BucketEncryptionInfoOutput, error)
+
+	GetBucketPublicAccessInfo(c context.Context,
+		params *s3client.BucketPublicAccessInfoInput) (s3client.BucketPublicAccessInfoOutput, error)
 }
 
 type S3Stats struct {
@@ -27,65 +47,392 @@ type S3Stats struct {
 }
 
 type GenerateBucketStatsInput struct {
-	GetReplicationRules bool
-	GetLifecycleRules   bool
-	FilterObjectPrefix  string
-	FilterBucketName    string
-	NumberOfThreads     int
+	GetReplicationRules    bool
+	GetLifecycleRules      bool
+	GetVersionStats        bool
+	GetMultipartUploads    bool
+	GetVersioningInfo      bool
+	GetEncryptionInfo      bool
+	GetPublicAccessInfo    bool
+	FilterObjectPrefixes   []string
+	ModifiedAfter          time.Time
+	ModifiedBefore         time.Time
+	MinSize                int64
+	MaxSize                int64
+	KeySuffixes            []string
+	KeyPattern             string
+	StorageClasses         []string
+	FilterBucketName       string
+	BucketNames            []string
+	IncludeBuckets         []string
+	ExcludeBuckets         []string
+	CreatedAfter           time.Time
+	CreatedBefore          time.Time
+	IncludeRegions         []string
+	ExcludeRegions         []string
+	NumberOfThreads        int
+	SizeDistributionBounds []int64
+	PrefixDepth            int
+	PrefixDelimiter        string
+	TopN                   int
+	ShardMode              string
+	ShardBoundaries        []string
+	BucketTimeout          time.Duration
+	CheckpointFile         string
+	CheckpointInterval     time.Duration
+	Resume                 bool
 }
 
 type GetBucketStatsInput struct {
-	GetReplicationRules bool
-	GetLifecycleRules   bool
-	FilterPrefix        string
+	GetReplicationRules    bool
+	GetLifecycleRules      bool
+	GetVersionStats        bool
+	GetMultipartUploads    bool
+	GetVersioningInfo      bool
+	GetEncryptionInfo      bool
+	GetPublicAccessInfo    bool
+	FilterPrefixes         []string
+	ObjectFilter           s3client.ObjectFilter
+	SizeDistributionBounds []int64
+	PrefixDepth            int
+	PrefixDelimiter        string
+	TopN                   int
+	ShardMode              string
+	ShardBoundaries        []string
+	BucketTimeout          time.Duration
 }
 type BucketStats struct {
 	Name                       string            `json:"name"`
 	CreationDate               time.Time         `json:"creation_date"`
+	Region                     string            `json:"region,omitempty"`
 	TotalFiles                 int64             `json:"total_files"`
+	SizeInBytes                int64             `json:"size_in_bytes"`
 	SizeInKB                   int64             `json:"size_in_kb"`
+	Size                       SizeUnits         `json:"size"`
 	MostRecentFile             string            `json:"most_recent_file"`
 	MostRecentFileModifiedDate time.Time         `json:"most_recent_file_modified_date"`
+	OldestFile                 string            `json:"oldest_file"`
+	OldestFileModifiedDate     time.Time         `json:"oldest_file_modified_date"`
+	LargestFile                string            `json:"largest_file"`
+	LargestFileSizeInBytes     int64             `json:"largest_file_size_in_bytes"`
+	SmallestFile               string            `json:"smallest_file"`
+	SmallestFileSizeInBytes    int64             `json:"smallest_file_size_in_bytes"`
+	AverageFileSizeInBytes     int64             `json:"average_file_size_in_bytes"`
+	StorageClasses             []StorageClass    `json:"storage_classes"`
+	SizeDistribution           []SizeRange       `json:"size_distribution"`
+	AgeDistribution            []AgeRange        `json:"age_distribution"`
+	Prefixes                   []PrefixStats     `json:"prefixes,omitempty"`
+	LargestFiles               []ObjectInfo      `json:"largest_files,omitempty"`
+	LargestPrefixes            []PrefixStats     `json:"largest_prefixes,omitempty"`
+	Versions                   *VersionStats     `json:"versions,omitempty"`
+	MultipartUploads           *MultipartStats   `json:"multipart_uploads,omitempty"`
+	Versioning                 *VersioningInfo   `json:"versioning,omitempty"`
+	ObjectLock                 *ObjectLockInfo   `json:"object_lock,omitempty"`
+	Encryption                 *EncryptionInfo   `json:"encryption,omitempty"`
+	PublicExposure             string            `json:"public_exposure,omitempty"`
+	PublicAccess               *PublicAccessInfo `json:"public_access,omitempty"`
+	ReplicationRole            string            `json:"replication_role,omitempty"`
 	ReplicationRules           []ReplicationRule `json:"replication_rules"`
 	LifecycleRules             []LifecycleRule   `json:"lifecyle_rules"`
+	Error                      *BucketError      `json:"error,omitempty"`
 }
 
 type GenerateBucketStatsOutput struct {
 	BucketsStats []BucketStats
+	Summary      Summary
+	Regions      []RegionStats
+	Filters      Filters
+	Incomplete   bool
+}
+
+// Filters records the bucket and object filters of a run, so the stats of a
+// report can be reproduced. Notes explain how the object filters apply to the
+// sections whose entries lack some of the fields filtered.
+type Filters struct {
+	BucketName     string     `json:"bucket_name,omitempty"`
+	BucketNames    []string   `json:"bucket_names,omitempty"`
+	IncludeBuckets []string   `json:"include_buckets,omitempty"`
+	ExcludeBuckets []string   `json:"exclude_buckets,omitempty"`
+	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	CreatedBefore  *time.Time `json:"created_before,omitempty"`
+	IncludeRegions []string   `json:"include_regions,omitempty"`
+	ExcludeRegions []string   `json:"exclude_regions,omitempty"`
+	ObjectPrefixes []string   `json:"object_prefixes,omitempty"`
+	ModifiedAfter  *time.Time `json:"modified_after,omitempty"`
+	ModifiedBefore *time.Time `json:"modified_before,omitempty"`
+	MinSize        int64      `json:"min_size,omitempty"`
+	MaxSize        int64      `json:"max_size,omitempty"`
+	KeySuffixes    []string   `json:"key_suffixes,omitempty"`
+	KeyPattern     string     `json:"key_pattern,omitempty"`
+	StorageClasses []string   `json:"storage_classes,omitempty"`
+	Notes          []string   `json:"notes,omitempty"`
+}
+
+func NewFilters(params *GenerateBucketStatsInput) Filters {
+	f := Filters{
+		BucketName:     params.FilterBucketName,
+		BucketNames:    params.BucketNames,
+		IncludeBuckets: params.IncludeBuckets,
+		ExcludeBuckets: params.ExcludeBuckets,
+		CreatedAfter:   timeOrNil(params.CreatedAfter),
+		CreatedBefore:  timeOrNil(params.CreatedBefore),
+		IncludeRegions: params.IncludeRegions,
+		ExcludeRegions: params.ExcludeRegions,
+		ObjectPrefixes: params.FilterObjectPrefixes,
+		ModifiedAfter:  timeOrNil(params.ModifiedAfter),
+		ModifiedBefore: timeOrNil(params.ModifiedBefore),
+		MinSize:        params.MinSize,
+		MaxSize:        params.MaxSize,
+		KeySuffixes:    params.KeySuffixes,
+		KeyPattern:     params.KeyPattern,
+		StorageClasses: params.StorageClasses,
+	}
+
+	size := params.MinSize > 0 || params.MaxSize > 0
+	modified := !params.ModifiedAfter.IsZero() || !params.ModifiedBefore.IsZero()
+	if params.GetVersionStats && (size || len(params.StorageClasses) > 0) {
+		f.Notes = append(f.Notes, "versions: delete markers are filtered by key and modified date only")
+	}
+	if params.GetMultipartUploads && (size || modified) {
+		f.Notes = append(f.Notes, "multipart_uploads: filtered by initiation date as modified date and by uploaded parts size")
+	}
+	return f
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+type Summary struct {
+	TotalBuckets       int            `json:"total_buckets"`
+	SucceededBuckets   int            `json:"succeeded_buckets"`
+	FailedBuckets      int            `json:"failed_buckets"`
+	SkippedBuckets     int            `json:"skipped_buckets"`
+	FailuresByCategory map[string]int `json:"failures_by_category,omitempty"`
+}
+
+type RegionStats struct {
+	Region       string    `json:"region"`
+	TotalBuckets int       `json:"total_buckets"`
+	TotalFiles   int64     `json:"total_files"`
+	SizeInBytes  int64     `json:"size_in_bytes"`
+	Size         SizeUnits `json:"size"`
+}
+
+type SizeUnits struct {
+	KiB   float64 `json:"kib"`
+	MiB   float64 `json:"mib"`
+	GiB   float64 `json:"gib"`
+	TiB   float64 `json:"tib"`
+	Human string  `json:"human"`
+}
+
+type StorageClass struct {
+	StorageClass string `json:"storage_class"`
+	TotalFiles   int64  `json:"total_files"`
+	SizeInBytes  int64  `json:"size_in_bytes"`
+}
+
+type SizeRange struct {
+	Label          string `json:"label"`
+	MinSizeInBytes int64  `json:"min_size_in_bytes"`
+	MaxSizeInBytes int64  `json:"max_size_in_bytes,omitempty"`
+	TotalFiles     int64  `json:"total_files"`
+	SizeInBytes    int64  `json:"size_in_bytes"`
+}
+
+type AgeRange struct {
+	Label        string `json:"label"`
+	MinAgeInDays int    `json:"min_age_in_days"`
+	MaxAgeInDays int    `json:"max_age_in_days,omitempty"`
+	TotalFiles   int64  `json:"total_files"`
+	SizeInBytes  int64  `json:"size_in_bytes"`
+}
+
+type PrefixStats struct {
+	Prefix                     string        `json:"prefix"`
+	TotalFiles                 int64         `json:"total_files"`
+	SizeInBytes                int64         `json:"size_in_bytes"`
+	MostRecentFileModifiedDate time.Time     `json:"most_recent_file_modified_date"`
+	Prefixes                   []PrefixStats `json:"prefixes,omitempty"`
+}
+
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	SizeInBytes  int64     `json:"size_in_bytes"`
+	StorageClass string    `json:"storage_class"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type VersionStats struct {
+	CurrentFiles                     int64     `json:"current_files"`
+	CurrentSizeInBytes               int64     `json:"current_size_in_bytes"`
+	NoncurrentFiles                  int64     `json:"noncurrent_files"`
+	NoncurrentSizeInBytes            int64     `json:"noncurrent_size_in_bytes"`
+	DeleteMarkers                    int64     `json:"delete_markers"`
+	OldestNoncurrentFile             string    `json:"oldest_noncurrent_file,omitempty"`
+	OldestNoncurrentFileModifiedDate time.Time `json:"oldest_noncurrent_file_modified_date"`
+}
+
+type MultipartStats struct {
+	TotalUploads                  int64             `json:"total_uploads"`
+	SizeInBytes                   int64             `json:"size_in_bytes"`
+	HasAbortIncompleteUploadsRule bool              `json:"has_abort_incomplete_uploads_rule"`
+	Uploads                       []MultipartUpload `json:"uploads"`
+}
+
+type MultipartUpload struct {
+	Key          string    `json:"key"`
+	UploadID     string    `json:"upload_id"`
+	Initiated    time.Time `json:"initiated"`
+	StorageClass string    `json:"storage_class"`
+	TotalParts   int64     `json:"total_parts"`
+	SizeInBytes  int64     `json:"size_in_bytes"`
+}
+
+type VersioningInfo struct {
+	Status    string `json:"status"`
+	MFADelete string `json:"mfa_delete"`
+}
+
+type ObjectLockInfo struct {
+	Enabled        bool   `json:"enabled"`
+	RetentionMode  string `json:"retention_mode,omitempty"`
+	RetentionDays  int32  `json:"retention_days,omitempty"`
+	RetentionYears int32  `json:"retention_years,omitempty"`
+}
+
+type EncryptionInfo struct {
+	Algorithm        string `json:"algorithm"`
+	KMSMasterKeyID   string `json:"kms_master_key_id,omitempty"`
+	BucketKeyEnabled bool   `json:"bucket_key_enabled"`
+}
+
+type PublicAccessInfo struct {
+	PublicAccessBlockConfigured bool     `json:"public_access_block_configured"`
+	BlockPublicAcls             bool     `json:"block_public_acls"`
+	IgnorePublicAcls            bool     `json:"ignore_public_acls"`
+	BlockPublicPolicy           bool     `json:"block_public_policy"`
+	RestrictPublicBuckets       bool     `json:"restrict_public_buckets"`
+	PolicyIsPublic              bool     `json:"policy_is_public"`
+	PublicPolicyStatements      []string `json:"public_policy_statements,omitempty"`
+	PublicACLGrants             []string `json:"public_acl_grants,omitempty"`
+	Policy                      string   `json:"policy,omitempty"`
 }
 
 type ReplicationRule struct {
-	DestinationBucket  string `json:"destination_bucket"`
-	DestinationAccount string `json:"destination_account"`
-	StorageClass       string `json:"storage_class"`
-	ID                 string `json:"id"`
-	Priority           int32  `json:"priority"`
-	Status             string `json:"status"`
+	DestinationBucket            string                `json:"destination_bucket"`
+	DestinationAccount           string                `json:"destination_account"`
+	StorageClass                 string                `json:"storage_class"`
+	ID                           string                `json:"id"`
+	Priority                     int32                 `json:"priority"`
+	Status                       string                `json:"status"`
+	Filter                       ReplicationRuleFilter `json:"filter"`
+	DeleteMarkerReplication      string                `json:"delete_marker_replication,omitempty"`
+	ExistingObjectReplication    string                `json:"existing_object_replication,omitempty"`
+	ReplicaModifications         string                `json:"replica_modifications,omitempty"`
+	SseKmsEncryptedObjects       string                `json:"sse_kms_encrypted_objects,omitempty"`
+	ReplicationTimeControl       string                `json:"replication_time_control,omitempty"`
+	ReplicationTimeMinutes       int32                 `json:"replication_time_minutes,omitempty"`
+	Metrics                      string                `json:"metrics,omitempty"`
+	MetricsEventThresholdMinutes int32                 `json:"metrics_event_threshold_minutes,omitempty"`
+	ReplicaKmsKeyID              string                `json:"replica_kms_key_id,omitempty"`
+	OwnerOverride                string                `json:"owner_override,omitempty"`
+}
+
+type ReplicationRuleFilter struct {
+	Prefix string `json:"prefix,omitempty"`
+	Tags   []Tag  `json:"tags,omitempty"`
+	And    bool   `json:"and"`
 }
 
 type LifecycleRule struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+	ID                                 string                        `json:"id"`
+	Status                             string                        `json:"status"`
+	Filter                             LifecycleRuleFilter           `json:"filter"`
+	Transitions                        []LifecycleTransition         `json:"transitions,omitempty"`
+	ExpirationDays                     int32                         `json:"expiration_days,omitempty"`
+	ExpirationDate                     *time.Time                    `json:"expiration_date,omitempty"`
+	ExpiredObjectDeleteMarker          bool                          `json:"expired_object_delete_marker"`
+	NoncurrentVersionTransitions       []NoncurrentVersionTransition `json:"noncurrent_version_transitions,omitempty"`
+	NoncurrentVersionExpirationDays    int32                         `json:"noncurrent_version_expiration_days,omitempty"`
+	AbortIncompleteMultipartUploadDays int32                         `json:"abort_incomplete_multipart_upload_days,omitempty"`
 }
 
-func NewS3Stats() *S3Stats {
-	return &S3Stats{
-		Api: s3client.NewS3Client(),
+type LifecycleRuleFilter struct {
+	Prefix string `json:"prefix,omitempty"`
+	Tags   []Tag  `json:"tags,omitempty"`
+	And    bool   `json:"and"`
+}
+
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type LifecycleTransition struct {
+	Days         int32      `json:"days,omitempty"`
+	Date         *time.Time `json:"date,omitempty"`
+	StorageClass string     `json:"storage_class"`
+}
+
+type NoncurrentVersionTransition struct {
+	NoncurrentDays int32  `json:"noncurrent_days"`
+	StorageClass   string `json:"storage_class"`
+}
+
+func NewS3Stats(c context.Context, cc s3client.ClientConfig) (*S3Stats, error) {
+	s3c, err := s3client.NewS3Client(c, cc)
+	if err != nil {
+		return nil, err
 	}
+	return &S3Stats{Api: s3c}, nil
 }
 
-var (
-	lock = sync.Mutex{}
-	bsl  = []BucketStats{}
-	wg   = sync.WaitGroup{}
-)
+// bucketStatsRun holds the state shared by the workers of a single
+// GenerateBucketStats call. limiter is shared by the bucket workers and the
+// shard listings of each bucket, so NumberOfThreads bounds the listings in flight.
+type bucketStatsRun struct {
+	limiter     s3client.Semaphore
+	lock        sync.Mutex
+	wg          sync.WaitGroup
+	bsl         []BucketStats
+	skipped     int
+	interrupted int
+	progress    map[string]*s3client.Progress
+	resume      map[string]s3client.ObjectStatsProgress
+}
+
+// bucketListing holds what the object listing of a single bucket shares with
+// the run it belongs to.
+type bucketListing struct {
+	limiter  s3client.Semaphore
+	progress *s3client.Progress
+	resume   *s3client.ObjectStatsProgress
+}
 
-func (s3s S3Stats) GenerateBucketStats(params *GenerateBucketStatsInput) (GenerateBucketStatsOutput, error) {
-	if params.NumberOfThreads == 0 {
-		params.NumberOfThreads = 1
+// GenerateBucketStats collects the stats of every bucket until ctx is done. Once
+// ctx is done no more buckets are started and the stats completed so far are
+// returned with Incomplete set.
+func (s3s S3Stats) GenerateBucketStats(ctx context.Context, params *GenerateBucketStatsInput) (GenerateBucketStatsOutput, error) {
+	numberOfThreads := params.NumberOfThreads
+	if numberOfThreads <= 0 {
+		numberOfThreads = 1
 	}
 
-	bl, err := s3s.Api.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{FilterBucketName: params.FilterBucketName})
+	bl, err := s3s.Api.GetAllBuckets(ctx, &s3client.AllBucketsInput{
+		FilterBucketName: params.FilterBucketName,
+		BucketNames:      params.BucketNames,
+		IncludeBuckets:   params.IncludeBuckets,
+		ExcludeBuckets:   params.ExcludeBuckets,
+		CreatedAfter:     params.CreatedAfter,
+		CreatedBefore:    params.CreatedBefore,
+		IncludeRegions:   params.IncludeRegions,
+		ExcludeRegions:   params.ExcludeRegions,
+	})
 	if err != nil {
 		log.Error("Error while getting bucket list: ", err)
 		return GenerateBucketStatsOutput{}, err
@@ -98,93 +445,549 @@ func (s3s S3Stats) GenerateBucketStats(params *GenerateBucketStatsInput) (Genera
 
 	inputChannel := make(chan s3client.Bucket, len(bl.Buckets))
 
-	log.Infof("Creating %v workers for concurrent running", params.NumberOfThreads)
+	log.Infof("Creating %v workers for concurrent running", numberOfThreads)
 	p := GetBucketStatsInput{
 		GetReplicationRules: params.GetReplicationRules,
 		GetLifecycleRules:   params.GetLifecycleRules,
-		FilterPrefix:        params.FilterObjectPrefix,
+		GetVersionStats:     params.GetVersionStats,
+		GetMultipartUploads: params.GetMultipartUploads,
+		GetVersioningInfo:   params.GetVersioningInfo,
+		GetEncryptionInfo:   params.GetEncryptionInfo,
+		GetPublicAccessInfo: params.GetPublicAccessInfo,
+		FilterPrefixes:      params.FilterObjectPrefixes,
+		ObjectFilter: s3client.ObjectFilter{
+			ModifiedAfter:  params.ModifiedAfter,
+			ModifiedBefore: params.ModifiedBefore,
+			MinSize:        params.MinSize,
+			MaxSize:        params.MaxSize,
+			Suffixes:       params.KeySuffixes,
+			KeyPattern:     params.KeyPattern,
+			StorageClasses: params.StorageClasses,
+		},
+		SizeDistributionBounds: params.SizeDistributionBounds,
+		PrefixDepth:            params.PrefixDepth,
+		PrefixDelimiter:        params.PrefixDelimiter,
+		TopN:                   params.TopN,
+		ShardMode:              params.ShardMode,
+		ShardBoundaries:        params.ShardBoundaries,
+		BucketTimeout:          params.BucketTimeout,
 	}
-	for i := 0; i < params.NumberOfThreads; i++ {
-		go s3s.getBucketStats(inputChannel, &p)
+	run := &bucketStatsRun{
+		limiter:  s3client.NewSemaphore(numberOfThreads),
+		progress: map[string]*s3client.Progress{},
+		resume:   map[string]s3client.ObjectStatsProgress{},
 	}
-	wg.Add(params.NumberOfThreads)
 
+	completed := map[string]BucketStats{}
+	if params.Resume {
+		cp, err := LoadCheckpoint(params.CheckpointFile)
+		if err != nil {
+			log.Error("Error while loading checkpoint: ", err)
+			return GenerateBucketStatsOutput{}, err
+		}
+		if err := cp.validate(params); err != nil {
+			return GenerateBucketStatsOutput{}, err
+		}
+		for _, bs := range cp.Completed {
+			completed[bs.Name] = bs
+		}
+		for name, pr := range cp.InProgress {
+			run.resume[name] = pr
+		}
+		log.Infof("Resuming from %v with %v buckets completed and %v in progress",
+			params.CheckpointFile, len(cp.Completed), len(cp.InProgress))
+	}
+
+	run.wg.Add(numberOfThreads)
+	for i := 0; i < numberOfThreads; i++ {
+		go s3s.getBucketStats(ctx, inputChannel, &p, run)
+	}
+
+	stopCheckpoints := make(chan struct{})
+	checkpointsDone := make(chan struct{})
+	go s3s.saveCheckpoints(params, run, stopCheckpoints, checkpointsDone)
+
+	// Buckets completed in the checkpoint are kept even when the run is stopped,
+	// so that only the buckets not queued are counted as skipped.
+	stopped := false
 	for _, b := range bl.Buckets {
+		if bs, ok := completed[b.Name]; ok {
+			log.Infof("Skipping %v, already completed in checkpoint", b.Name)
+			run.lock.Lock()
+			run.bsl = append(run.bsl, bs)
+			run.lock.Unlock()
+			continue
+		}
+		if ctx.Err() != nil {
+			if !stopped {
+				log.Warnf("Stopped queueing buckets: %v", ctx.Err())
+				stopped = true
+			}
+			run.lock.Lock()
+			run.skipped++
+			run.lock.Unlock()
+			continue
+		}
 		log.Infof("Queeing %v for processing", b.Name)
 		inputChannel <- b
 	}
 
 	close(inputChannel)
-	wg.Wait()
+	run.wg.Wait()
+	close(stopCheckpoints)
+	<-checkpointsDone
+
+	sort.Slice(run.bsl, func(i, j int) bool { return run.bsl[i].Name < run.bsl[j].Name })
+
+	sm := NewSummary(run.bsl)
+	sm.TotalBuckets += run.skipped
+	sm.SkippedBuckets = run.skipped
+	incomplete := run.skipped > 0 || run.interrupted > 0
+
+	if params.CheckpointFile != "" {
+		if sm.FailedBuckets == 0 && !incomplete {
+			if err := os.Remove(params.CheckpointFile); err != nil && !os.IsNotExist(err) {
+				log.Warn("Error while removing checkpoint: ", err)
+			}
+		} else if err := s3s.saveCheckpoint(params, run); err != nil {
+			log.Error("Error while saving checkpoint: ", err)
+		} else {
+			log.Warnf("Progress saved to %v, run again with -resume to continue", params.CheckpointFile)
+		}
+	}
+
+	return GenerateBucketStatsOutput{
+		BucketsStats: run.bsl,
+		Summary:      sm,
+		Regions:      NewRegionStats(run.bsl),
+		Filters:      NewFilters(params),
+		Incomplete:   incomplete,
+	}, nil
+}
+
+// saveCheckpoints saves a checkpoint every CheckpointInterval until stop is closed.
+func (s3s S3Stats) saveCheckpoints(params *GenerateBucketStatsInput, run *bucketStatsRun, stop, done chan struct{}) {
+	defer close(done)
+	if params.CheckpointFile == "" || params.CheckpointInterval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(params.CheckpointInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			if err := s3s.saveCheckpoint(params, run); err != nil {
+				log.Error("Error while saving checkpoint: ", err)
+			}
+		}
+	}
+}
+
+// saveCheckpoint saves the buckets completed so far along with a snapshot of the
+// listing of the buckets in progress or failed. Buckets resumed from the previous
+// checkpoint that were not started again keep their previous progress.
+func (s3s S3Stats) saveCheckpoint(params *GenerateBucketStatsInput, run *bucketStatsRun) error {
+	cp := Checkpoint{
+		Input:      checkpointInput(params),
+		InProgress: map[string]s3client.ObjectStatsProgress{},
+	}
+
+	run.lock.Lock()
+	for _, bs := range run.bsl {
+		if bs.Error == nil {
+			cp.Completed = append(cp.Completed, bs)
+		}
+	}
+	for name, pr := range run.resume {
+		cp.InProgress[name] = pr
+	}
+	for name, pr := range run.progress {
+		if snapshot, ok := pr.Snapshot(); ok {
+			cp.InProgress[name] = snapshot
+		}
+	}
+	run.lock.Unlock()
+
+	return cp.Save(params.CheckpointFile)
+}
+
+func NewSummary(bsl []BucketStats) Summary {
+	s := Summary{TotalBuckets: len(bsl)}
+	for _, bs := range bsl {
+		if bs.Error == nil {
+			s.SucceededBuckets += 1
+			continue
+		}
+		if s.FailuresByCategory == nil {
+			s.FailuresByCategory = map[string]int{}
+		}
+		s.FailedBuckets += 1
+		s.FailuresByCategory[bs.Error.Category] += 1
+	}
+	return s
+}
+
+// UnknownRegion groups the buckets whose region could not be resolved.
+const UnknownRegion = "unknown"
+
+// NewRegionStats totals the buckets, files and bytes of each region, sorted by region.
+func NewRegionStats(bsl []BucketStats) []RegionStats {
+	idx := map[string]int{}
+	var r []RegionStats
+	for _, bs := range bsl {
+		region := bs.Region
+		if region == "" {
+			region = UnknownRegion
+		}
+		i, ok := idx[region]
+		if !ok {
+			i = len(r)
+			idx[region] = i
+			r = append(r, RegionStats{Region: region})
+		}
+		r[i].TotalBuckets += 1
+		r[i].TotalFiles += bs.TotalFiles
+		r[i].SizeInBytes += bs.SizeInBytes
+	}
 
-	return GenerateBucketStatsOutput{BucketsStats: bsl}, nil
+	sort.Slice(r, func(i, j int) bool { return r[i].Region < r[j].Region })
+	return r
 }
 
-func (s3s S3Stats) getBucketStats(inputChannel chan s3client.Bucket, params *GetBucketStatsInput) {
+func (s3s S3Stats) getBucketStats(ctx context.Context, inputChannel chan s3client.Bucket, params *GetBucketStatsInput, run *bucketStatsRun) {
 	for b := range inputChannel {
+		if ctx.Err() != nil || run.limiter.Acquire(ctx) != nil {
+			log.Infof("Skipping bucket %v: %v", b.Name, ctx.Err())
+			run.lock.Lock()
+			run.skipped += 1
+			run.lock.Unlock()
+			continue
+		}
+
+		l := bucketListing{limiter: run.limiter, progress: s3client.NewProgress()}
+		run.lock.Lock()
+		if pr, ok := run.resume[b.Name]; ok {
+			l.resume = &pr
+		}
+		run.progress[b.Name] = l.progress
+		run.lock.Unlock()
 
-		log.Infof("Getting stats for bucket %v", b.Name)
-		bs, err := s3s.Api.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: b.Name, Prefix: params.FilterPrefix})
+		bctx, cancel := ctx, context.CancelFunc(func() {})
+		if params.BucketTimeout > 0 {
+			bctx, cancel = context.WithTimeout(ctx, params.BucketTimeout)
+		}
+		bs, err := s3s.getStats(bctx, b, params, l)
+		cancel()
+		run.limiter.Release()
 		if err != nil {
-			log.Error("Error while getting object stats: ", err)
-			wg.Done()
-			return
+			log.Errorf("Error while getting stats for bucket %v: %v", b.Name, err)
+			bs.Error = &BucketError{Category: ErrorCategory(err), Message: err.Error()}
 		}
 
-		var repRules []ReplicationRule
+		run.lock.Lock()
+		if err != nil && ctx.Err() != nil {
+			run.interrupted += 1
+		}
+		if err == nil {
+			delete(run.progress, b.Name)
+			delete(run.resume, b.Name)
+		}
+		run.bsl = append(run.bsl, bs)
+		run.lock.Unlock()
+	}
+	run.wg.Done()
+}
 
-		if params.GetReplicationRules {
-			log.Infof("Getting replication info for bucket %v", b.Name)
-			bri, err := s3s.Api.GetBucketReplicationInfo(context.TODO(), &s3client.BucketReplicationInfoInput{BucketName: b.Name})
-			if err != nil {
-				log.Error("Error while getting replication info stats: ", err)
-				wg.Done()
-				return
-			}
+// getStats collects every requested stat of a single bucket. On error it returns
+// whatever was collected before the failing call along with the error.
+func (s3s S3Stats) getStats(ctx context.Context, b s3client.Bucket, params *GetBucketStatsInput,
+	l bucketListing) (BucketStats, error) {
+	r := BucketStats{
+		Name:         b.Name,
+		CreationDate: b.CreationDate,
+		Region:       b.Region,
+	}
 
-			for _, rr := range bri.ReplicationRules {
-				repRules = append(repRules, ReplicationRule{
-					DestinationAccount: rr.DestinationAccount,
-					DestinationBucket:  rr.DestinationBucket,
-					StorageClass:       rr.StorageClass,
-					ID:                 rr.ID,
-					Priority:           rr.Priority,
-					Status:             rr.Status,
-				})
-			}
+	log.Infof("Getting stats for bucket %v", b.Name)
+	bs, err := s3s.Api.GetObjectStats(ctx, &s3client.ObjectStatsInput{
+		BucketName:             b.Name,
+		Prefixes:               params.FilterPrefixes,
+		Filter:                 params.ObjectFilter,
+		SizeDistributionBounds: params.SizeDistributionBounds,
+		PrefixDepth:            params.PrefixDepth,
+		Delimiter:              params.PrefixDelimiter,
+		TopN:                   params.TopN,
+		ShardMode:              params.ShardMode,
+		ShardBoundaries:        params.ShardBoundaries,
+		Limiter:                l.limiter,
+		Progress:               l.progress,
+		Resume:                 l.resume,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	r.TotalFiles = bs.TotalFiles
+	r.SizeInBytes = bs.SizeInBytes
+	r.SizeInKB = bs.SizeInKB
+	r.MostRecentFile = bs.MostRecentFile
+	r.MostRecentFileModifiedDate = bs.MostRecentFileModifiedDate
+	r.OldestFile = bs.OldestFile
+	r.OldestFileModifiedDate = bs.OldestFileModifiedDate
+	r.LargestFile = bs.LargestFile
+	r.LargestFileSizeInBytes = bs.LargestFileSizeInBytes
+	r.SmallestFile = bs.SmallestFile
+	r.SmallestFileSizeInBytes = bs.SmallestFileSizeInBytes
+	r.AverageFileSizeInBytes = bs.AverageFileSizeInBytes
+	r.Prefixes = newPrefixStats(bs.Prefixes)
+	r.LargestPrefixes = newPrefixStats(bs.LargestPrefixes)
+
+	for _, sc := range bs.StorageClasses {
+		r.StorageClasses = append(r.StorageClasses, StorageClass{
+			StorageClass: sc.StorageClass,
+			TotalFiles:   sc.TotalFiles,
+			SizeInBytes:  sc.SizeInBytes,
+		})
+	}
+
+	for _, sr := range bs.SizeDistribution {
+		r.SizeDistribution = append(r.SizeDistribution, SizeRange{
+			Label:          sr.Label,
+			MinSizeInBytes: sr.MinSizeInBytes,
+			MaxSizeInBytes: sr.MaxSizeInBytes,
+			TotalFiles:     sr.TotalFiles,
+			SizeInBytes:    sr.SizeInBytes,
+		})
+	}
+
+	for _, ar := range bs.AgeDistribution {
+		r.AgeDistribution = append(r.AgeDistribution, AgeRange{
+			Label:        ar.Label,
+			MinAgeInDays: ar.MinAgeInDays,
+			MaxAgeInDays: ar.MaxAgeInDays,
+			TotalFiles:   ar.TotalFiles,
+			SizeInBytes:  ar.SizeInBytes,
+		})
+	}
+
+	for _, oi := range bs.LargestFiles {
+		r.LargestFiles = append(r.LargestFiles, ObjectInfo{
+			Key:          oi.Key,
+			SizeInBytes:  oi.SizeInBytes,
+			StorageClass: oi.StorageClass,
+			LastModified: oi.LastModified,
+		})
+	}
+
+	if params.GetVersionStats {
+		log.Infof("Getting version stats for bucket %v", b.Name)
+		ovs, err := s3s.Api.GetObjectVersionStats(ctx, &s3client.ObjectVersionStatsInput{BucketName: b.Name,
+			Prefixes: params.FilterPrefixes, Filter: params.ObjectFilter})
+		if err != nil {
+			return r, err
+		}
+		r.Versions = &VersionStats{
+			CurrentFiles:                     ovs.CurrentFiles,
+			CurrentSizeInBytes:               ovs.CurrentSizeInBytes,
+			NoncurrentFiles:                  ovs.NoncurrentFiles,
+			NoncurrentSizeInBytes:            ovs.NoncurrentSizeInBytes,
+			DeleteMarkers:                    ovs.DeleteMarkers,
+			OldestNoncurrentFile:             ovs.OldestNoncurrentFile,
+			OldestNoncurrentFileModifiedDate: ovs.OldestNoncurrentFileModifiedDate,
+		}
+	}
+
+	if params.GetMultipartUploads {
+		log.Infof("Getting multipart uploads for bucket %v", b.Name)
+		mus, err := s3s.Api.GetMultipartUploadStats(ctx, &s3client.MultipartUploadStatsInput{BucketName: b.Name,
+			Prefixes: params.FilterPrefixes, Filter: params.ObjectFilter})
+		if err != nil {
+			return r, err
+		}
+		ms := &MultipartStats{
+			TotalUploads:                  mus.TotalUploads,
+			SizeInBytes:                   mus.SizeInBytes,
+			HasAbortIncompleteUploadsRule: mus.HasAbortIncompleteUploadsRule,
+		}
+		for _, u := range mus.Uploads {
+			ms.Uploads = append(ms.Uploads, MultipartUpload{
+				Key:          u.Key,
+				UploadID:     u.UploadID,
+				Initiated:    u.Initiated,
+				StorageClass: u.StorageClass,
+				TotalParts:   u.TotalParts,
+				SizeInBytes:  u.SizeInBytes,
+			})
 		}
+		r.MultipartUploads = ms
+	}
 
-		var lcRules []LifecycleRule
+	if params.GetVersioningInfo {
+		log.Infof("Getting versioning info for bucket %v", b.Name)
+		bvi, err := s3s.Api.GetBucketVersioningInfo(ctx, &s3client.BucketVersioningInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+		r.Versioning = &VersioningInfo{
+			Status:    bvi.Status,
+			MFADelete: bvi.MFADelete,
+		}
 
-		if params.GetLifecycleRules {
-			log.Infof("Getting lifecycle info for bucket %v", b.Name)
-			lcr, err := s3s.Api.GetBucketLifecycleInfo(context.TODO(), &s3client.BucketLifeCycleInfoInput{BucketName: b.Name})
-			if err != nil {
-				log.Error("Error while getting lifecycle info stats: ", err)
-				wg.Done()
-				return
-			}
-			for _, lc := range lcr.LifeCycleRules {
-				lcRules = append(lcRules, LifecycleRule{
-					ID:     lc.ID,
-					Status: lc.Status,
-				})
-			}
+		log.Infof("Getting object lock info for bucket %v", b.Name)
+		boli, err := s3s.Api.GetBucketObjectLockInfo(ctx, &s3client.BucketObjectLockInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+		r.ObjectLock = &ObjectLockInfo{
+			Enabled:        boli.Enabled,
+			RetentionMode:  boli.RetentionMode,
+			RetentionDays:  boli.RetentionDays,
+			RetentionYears: boli.RetentionYears,
+		}
+	}
 
+	if params.GetEncryptionInfo {
+		log.Infof("Getting encryption info for bucket %v", b.Name)
+		bei, err := s3s.Api.GetBucketEncryptionInfo(ctx, &s3client.BucketEncryptionInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+		r.Encryption = &EncryptionInfo{
+			Algorithm:        bei.Algorithm,
+			KMSMasterKeyID:   bei.KMSMasterKeyID,
+			BucketKeyEnabled: bei.BucketKeyEnabled,
 		}
+	}
 
-		log.Infof("Generating ouput data for bucket %v", b.Name)
-		lock.Lock()
-		bsl = append(bsl, BucketStats{
-			Name:                       b.Name,
-			CreationDate:               b.CreationDate,
-			TotalFiles:                 bs.TotalFiles,
-			SizeInKB:                   bs.SizeInKB,
-			MostRecentFileModifiedDate: bs.MostRecentFileModifiedDate,
-			ReplicationRules:           repRules,
-			LifecycleRules:             lcRules,
+	if params.GetPublicAccessInfo {
+		log.Infof("Getting public access info for bucket %v", b.Name)
+		bpai, err := s3s.Api.GetBucketPublicAccessInfo(ctx, &s3client.BucketPublicAccessInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+		r.PublicExposure = bpai.Exposure
+		r.PublicAccess = &PublicAccessInfo{
+			PublicAccessBlockConfigured: bpai.PublicAccessBlockConfigured,
+			BlockPublicAcls:             bpai.BlockPublicAcls,
+			IgnorePublicAcls:            bpai.IgnorePublicAcls,
+			BlockPublicPolicy:           bpai.BlockPublicPolicy,
+			RestrictPublicBuckets:       bpai.RestrictPublicBuckets,
+			PolicyIsPublic:              bpai.PolicyIsPublic,
+			PublicPolicyStatements:      bpai.PublicPolicyStatements,
+			PublicACLGrants:             bpai.PublicACLGrants,
+			Policy:                      bpai.Policy,
+		}
+	}
+
+	if params.GetReplicationRules {
+		log.Infof("Getting replication info for bucket %v", b.Name)
+		bri, err := s3s.Api.GetBucketReplicationInfo(ctx, &s3client.BucketReplicationInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+
+		r.ReplicationRole = bri.Role
+		for _, rr := range bri.ReplicationRules {
+			r.ReplicationRules = append(r.ReplicationRules, newReplicationRule(rr))
+		}
+	}
+
+	if params.GetLifecycleRules {
+		log.Infof("Getting lifecycle info for bucket %v", b.Name)
+		lcr, err := s3s.Api.GetBucketLifecycleInfo(ctx, &s3client.BucketLifeCycleInfoInput{BucketName: b.Name})
+		if err != nil {
+			return r, err
+		}
+		for _, lc := range lcr.LifeCycleRules {
+			r.LifecycleRules = append(r.LifecycleRules, newLifecycleRule(lc))
+		}
+	}
+
+	log.Infof("Generating ouput data for bucket %v", b.Name)
+	return r, nil
+}
+
+func newPrefixStats(pss []s3client.PrefixStats) []PrefixStats {
+	var r []PrefixStats
+	for _, ps := range pss {
+		r = append(r, PrefixStats{
+			Prefix:                     ps.Prefix,
+			TotalFiles:                 ps.TotalFiles,
+			SizeInBytes:                ps.SizeInBytes,
+			MostRecentFileModifiedDate: ps.MostRecentFileModifiedDate,
+			Prefixes:                   newPrefixStats(ps.Prefixes),
+		})
+	}
+	return r
+}
+
+func newLifecycleRule(lc s3client.BucketLifeCycleRule) LifecycleRule {
+	lr := LifecycleRule{
+		ID:     lc.ID,
+		Status: lc.Status,
+		Filter: LifecycleRuleFilter{
+			Prefix: lc.Filter.Prefix,
+			And:    lc.Filter.And,
+		},
+		ExpirationDays:                     lc.ExpirationDays,
+		ExpirationDate:                     lc.ExpirationDate,
+		ExpiredObjectDeleteMarker:          lc.ExpiredObjectDeleteMarker,
+		NoncurrentVersionExpirationDays:    lc.NoncurrentVersionExpirationDays,
+		AbortIncompleteMultipartUploadDays: lc.AbortIncompleteMultipartUploadDays,
+	}
+
+	for _, t := range lc.Filter.Tags {
+		lr.Filter.Tags = append(lr.Filter.Tags, Tag{Key: t.Key, Value: t.Value})
+	}
+
+	for _, t := range lc.Transitions {
+		lr.Transitions = append(lr.Transitions, LifecycleTransition{
+			Days:         t.Days,
+			Date:         t.Date,
+			StorageClass: t.StorageClass,
+		})
+	}
+
+	for _, t := range lc.NoncurrentVersionTransitions {
+		lr.NoncurrentVersionTransitions = append(lr.NoncurrentVersionTransitions, NoncurrentVersionTransition{
+			NoncurrentDays: t.NoncurrentDays,
+			StorageClass:   t.StorageClass,
 		})
-		lock.Unlock()
 	}
-	wg.Done()
+
+	return lr
+}
+
+func newReplicationRule(rr s3client.BucketReplicationRule) ReplicationRule {
+	r := ReplicationRule{
+		DestinationAccount: rr.DestinationAccount,
+		DestinationBucket:  rr.DestinationBucket,
+		StorageClass:       rr.StorageClass,
+		ID:                 rr.ID,
+		Priority:           rr.Priority,
+		Status:             rr.Status,
+		Filter: ReplicationRuleFilter{
+			Prefix: rr.Filter.Prefix,
+			And:    rr.Filter.And,
+		},
+		DeleteMarkerReplication:      rr.DeleteMarkerReplication,
+		ExistingObjectReplication:    rr.ExistingObjectReplication,
+		ReplicaModifications:         rr.ReplicaModifications,
+		SseKmsEncryptedObjects:       rr.SseKmsEncryptedObjects,
+		ReplicationTimeControl:       rr.ReplicationTimeControl,
+		ReplicationTimeMinutes:       rr.ReplicationTimeMinutes,
+		Metrics:                      rr.Metrics,
+		MetricsEventThresholdMinutes: rr.MetricsEventThresholdMinutes,
+		ReplicaKmsKeyID:              rr.ReplicaKmsKeyID,
+		OwnerOverride:                rr.OwnerOverride,
+	}
+
+	for _, t := range rr.Filter.Tags {
+		r.Filter.Tags = append(r.Filter.Tags, Tag{Key: t.Key, Value: t.Value})
+	}
+
+	return r
 }
@@ -2,9 +2,14 @@ package s3stats_test
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/smithy-go"
 	"github.com/elribeiro/s3-stats-tool/internal/s3stats"
 	"github.com/elribeiro/s3-stats-tool/package/s3client"
 )
@@ -13,8 +18,8 @@ type S3ClientApiMock struct{}
 
 func (s3c S3ClientApiMock) GetAllBuckets(c context.Context, params *s3client.AllBucketsInput) (s3client.AllBucketsOutput, error) {
 	bs := []s3client.Bucket{
-		{Name: string("bucket1"), CreationDate: time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)},
-		{Name: string("bucket2"), CreationDate: time.Date(2020, time.April, 10, 22, 40, 20, 33, time.UTC)},
+		{Name: string("bucket1"), CreationDate: time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC), Region: "us-east-1"},
+		{Name: string("bucket2"), CreationDate: time.Date(2020, time.April, 10, 22, 40, 20, 33, time.UTC), Region: "eu-west-1"},
 	}
 
 	abo := s3client.AllBucketsOutput{
@@ -29,6 +34,10 @@ func (s3c S3ClientApiMock) GetObjectStats(c context.Context, params *s3client.Ob
 		TotalFiles:                 10,
 		SizeInKB:                   2500,
 		MostRecentFileModifiedDate: time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC),
+		StorageClasses: []s3client.StorageClass{
+			{StorageClass: "STANDARD", TotalFiles: 6, SizeInBytes: 1536000},
+			{StorageClass: "GLACIER", TotalFiles: 4, SizeInBytes: 1024000},
+		},
 	}
 
 	return &bs, nil
@@ -54,7 +63,7 @@ func (s3c S3ClientApiMock) GetBucketReplicationInfo(c context.Context,
 			ID:                 "id2",
 		}}
 
-	r := s3client.BucketReplicationInfoOutput{ReplicationRules: rr}
+	r := s3client.BucketReplicationInfoOutput{Role: "arn:aws:iam::123456789012:role/replication", ReplicationRules: rr}
 
 	return r, nil
 }
@@ -63,8 +72,10 @@ func (s3s S3ClientApiMock) GetBucketLifecycleInfo(c context.Context,
 	params *s3client.BucketLifeCycleInfoInput) (s3client.BucketLifeCycleInfoOutput, error) {
 	lcrs := []s3client.BucketLifeCycleRule{
 		{
-			Status: "Enabled",
-			ID:     "id1",
+			Status:      "Enabled",
+			ID:          "id1",
+			Filter:      s3client.LifeCycleRuleFilter{Prefix: "logs/"},
+			Transitions: []s3client.LifeCycleTransition{{Days: 30, StorageClass: "GLACIER"}},
 		},
 		{
 			Status: "Disabled",
@@ -76,6 +87,53 @@ func (s3s S3ClientApiMock) GetBucketLifecycleInfo(c context.Context,
 	return lco, nil
 }
 
+func (s3s S3ClientApiMock) GetObjectVersionStats(c context.Context,
+	params *s3client.ObjectVersionStatsInput) (s3client.ObjectVersionStatsOutput, error) {
+	vs := s3client.ObjectVersionStatsOutput{
+		CurrentFiles:          10,
+		CurrentSizeInBytes:    2560000,
+		NoncurrentFiles:       4,
+		NoncurrentSizeInBytes: 1024000,
+		DeleteMarkers:         2,
+	}
+
+	return vs, nil
+}
+
+func (s3s S3ClientApiMock) GetMultipartUploadStats(c context.Context,
+	params *s3client.MultipartUploadStatsInput) (s3client.MultipartUploadStatsOutput, error) {
+	mu := []s3client.MultipartUpload{
+		{Key: "item1", UploadID: "upload1", TotalParts: 2, SizeInBytes: 6291456},
+	}
+	ms := s3client.MultipartUploadStatsOutput{
+		TotalUploads: 1,
+		SizeInBytes:  6291456,
+		Uploads:      mu,
+	}
+
+	return ms, nil
+}
+
+func (s3s S3ClientApiMock) GetBucketVersioningInfo(c context.Context,
+	params *s3client.BucketVersioningInfoInput) (s3client.BucketVersioningInfoOutput, error) {
+	return s3client.BucketVersioningInfoOutput{Status: "Enabled", MFADelete: "Disabled"}, nil
+}
+
+func (s3s S3ClientApiMock) GetBucketObjectLockInfo(c context.Context,
+	params *s3client.BucketObjectLockInfoInput) (s3client.BucketObjectLockInfoOutput, error) {
+	return s3client.BucketObjectLockInfoOutput{Enabled: true, RetentionMode: "GOVERNANCE", RetentionDays: 10}, nil
+}
+
+func (s3s S3ClientApiMock) GetBucketEncryptionInfo(c context.Context,
+	params *s3client.BucketEncryptionInfoInput) (s3client.BucketEncryptionInfoOutput, error) {
+	return s3client.BucketEncryptionInfoOutput{Algorithm: "SSE-S3"}, nil
+}
+
+func (s3s S3ClientApiMock) GetBucketPublicAccessInfo(c context.Context,
+	params *s3client.BucketPublicAccessInfoInput) (s3client.BucketPublicAccessInfoOutput, error) {
+	return s3client.BucketPublicAccessInfoOutput{PublicAccessBlockConfigured: true, Exposure: "blocked"}, nil
+}
+
 func TestGenerateBucketStats(t *testing.T) {
 	result := &struct {
 		wantListSize         int
@@ -86,6 +144,14 @@ func TestGenerateBucketStats(t *testing.T) {
 		wantLifeCycleSize    int
 		wantReplicationID    string
 		wantReplicationSize  int
+		wantSCListSize       int
+		wantSCName           string
+		wantDeleteMarkers    int64
+		wantTotalUploads     int64
+		wantVersioning       string
+		wantObjectLockMode   string
+		wantEncryption       string
+		wantExposure         string
 	}{
 		wantListSize:         2,
 		wantFilteredListSize: 1,
@@ -95,17 +161,29 @@ func TestGenerateBucketStats(t *testing.T) {
 		wantLifeCycleSize:    2,
 		wantReplicationID:    "id1",
 		wantReplicationSize:  2,
+		wantSCListSize:       2,
+		wantSCName:           "STANDARD",
+		wantDeleteMarkers:    2,
+		wantTotalUploads:     1,
+		wantVersioning:       "Enabled",
+		wantObjectLockMode:   "GOVERNANCE",
+		wantEncryption:       "SSE-S3",
+		wantExposure:         "blocked",
 	}
 	thisTime := time.Now()
 	t.Log("Starting Test: ", thisTime)
 	api := S3ClientApiMock{}
 	s3s := s3stats.S3Stats{Api: api}
 
-	r, _ := s3s.GenerateBucketStats(&s3stats.GenerateBucketStatsInput{GetReplicationRules: true, GetLifecycleRules: true, NumberOfThreads: 10})
+	r, _ := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{GetReplicationRules: true, GetLifecycleRules: true, GetVersionStats: true, GetMultipartUploads: true, GetVersioningInfo: true, GetEncryptionInfo: true, GetPublicAccessInfo: true, NumberOfThreads: 10})
 	if len(r.BucketsStats) != result.wantListSize {
 		t.Errorf("Expecting %v, got %v", result.wantListSize, len(r.BucketsStats))
 	}
 
+	if r.BucketsStats[0].Region != result.wantRegion {
+		t.Errorf("Expecting %v, got %v", result.wantRegion, r.BucketsStats[0].Region)
+	}
+
 	if len(r.BucketsStats[0].LifecycleRules) != result.wantLifeCycleSize {
 		t.Errorf("Expecting %v, got %v", result.wantLifeCycleSize, len(r.BucketsStats[0].LifecycleRules))
 	}
@@ -118,8 +196,345 @@ func TestGenerateBucketStats(t *testing.T) {
 		t.Errorf("Expecting %v, got %v", result.wantLifeCycleID, r.BucketsStats[0].LifecycleRules[0].ID)
 	}
 
+	if r.BucketsStats[0].ReplicationRole == "" {
+		t.Errorf("Expecting replication role, got empty")
+	}
+
+	if len(r.BucketsStats[0].LifecycleRules[0].Transitions) != 1 || r.BucketsStats[0].LifecycleRules[0].Filter.Prefix != "logs/" {
+		t.Errorf("Expecting lifecycle filter and transitions, got %+v", r.BucketsStats[0].LifecycleRules[0])
+	}
+
 	if r.BucketsStats[0].ReplicationRules[0].ID != result.wantReplicationID {
 		t.Errorf("Expecting %v, got %v", result.wantReplicationID, r.BucketsStats[0].ReplicationRules[0].ID)
 	}
 
+	if r.BucketsStats[0].Versions == nil || r.BucketsStats[0].Versions.DeleteMarkers != result.wantDeleteMarkers {
+		t.Errorf("Expecting %v delete markers, got %+v", result.wantDeleteMarkers, r.BucketsStats[0].Versions)
+	}
+
+	if r.BucketsStats[0].MultipartUploads == nil || r.BucketsStats[0].MultipartUploads.TotalUploads != result.wantTotalUploads {
+		t.Errorf("Expecting %v uploads, got %+v", result.wantTotalUploads, r.BucketsStats[0].MultipartUploads)
+	}
+
+	if r.BucketsStats[0].Versioning == nil || r.BucketsStats[0].Versioning.Status != result.wantVersioning {
+		t.Errorf("Expecting %v, got %+v", result.wantVersioning, r.BucketsStats[0].Versioning)
+	}
+
+	if r.BucketsStats[0].ObjectLock == nil || r.BucketsStats[0].ObjectLock.RetentionMode != result.wantObjectLockMode {
+		t.Errorf("Expecting %v, got %+v", result.wantObjectLockMode, r.BucketsStats[0].ObjectLock)
+	}
+
+	if r.BucketsStats[0].Encryption == nil || r.BucketsStats[0].Encryption.Algorithm != result.wantEncryption {
+		t.Errorf("Expecting %v, got %+v", result.wantEncryption, r.BucketsStats[0].Encryption)
+	}
+
+	if r.BucketsStats[0].PublicExposure != result.wantExposure {
+		t.Errorf("Expecting %v, got %v", result.wantExposure, r.BucketsStats[0].PublicExposure)
+	}
+
+	if len(r.BucketsStats[0].StorageClasses) != result.wantSCListSize {
+		t.Errorf("Expecting %v, got %v", result.wantSCListSize, len(r.BucketsStats[0].StorageClasses))
+	}
+
+	if r.BucketsStats[0].StorageClasses[0].StorageClass != result.wantSCName {
+		t.Errorf("Expecting %v, got %v", result.wantSCName, r.BucketsStats[0].StorageClasses[0].StorageClass)
+	}
+
+}
+
+func TestNewSummary(t *testing.T) {
+	bsl := []s3stats.BucketStats{
+		{Name: "bucket1"},
+		{Name: "bucket2", Error: &s3stats.BucketError{Category: s3stats.ErrorAccessDenied}},
+		{Name: "bucket3", Error: &s3stats.BucketError{Category: s3stats.ErrorAccessDenied}},
+	}
+
+	s := s3stats.NewSummary(bsl)
+
+	if s.TotalBuckets != 3 {
+		t.Errorf("Expecting %v, got %v", 3, s.TotalBuckets)
+	}
+	if s.SucceededBuckets != 1 {
+		t.Errorf("Expecting %v, got %v", 1, s.SucceededBuckets)
+	}
+	if s.FailedBuckets != 2 {
+		t.Errorf("Expecting %v, got %v", 2, s.FailedBuckets)
+	}
+	if s.FailuresByCategory[s3stats.ErrorAccessDenied] != 2 {
+		t.Errorf("Expecting %v, got %v", 2, s.FailuresByCategory[s3stats.ErrorAccessDenied])
+	}
+}
+
+func TestNewRegionStats(t *testing.T) {
+	r := s3stats.NewRegionStats([]s3stats.BucketStats{
+		{Name: "bucket1", Region: "us-east-1", TotalFiles: 10, SizeInBytes: 100},
+		{Name: "bucket2", Region: "eu-west-1", TotalFiles: 20, SizeInBytes: 200},
+		{Name: "bucket3", Region: "us-east-1", TotalFiles: 30, SizeInBytes: 300},
+		{Name: "bucket4"},
+	})
+
+	if len(r) != 3 {
+		t.Fatalf("Expecting %v, got %v", 3, len(r))
+	}
+	if r[0].Region != "eu-west-1" || r[1].Region != s3stats.UnknownRegion || r[2].Region != "us-east-1" {
+		t.Errorf("Expecting regions sorted by name, got %+v", r)
+	}
+	if r[2].TotalBuckets != 2 || r[2].TotalFiles != 40 || r[2].SizeInBytes != 400 {
+		t.Errorf("Expecting 2 buckets, 40 files and 400 bytes, got %+v", r[2])
+	}
+}
+
+type S3ClientApiFailingMock struct {
+	S3ClientApiMock
+}
+
+func (s3c S3ClientApiFailingMock) GetObjectStats(c context.Context, params *s3client.ObjectStatsInput) (*s3client.ObjectStatsOutput, error) {
+	if params.BucketName == "bucket1" {
+		return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+	}
+	return s3c.S3ClientApiMock.GetObjectStats(c, params)
+}
+
+func TestGenerateBucketStatsWithErrors(t *testing.T) {
+	s3s := s3stats.S3Stats{Api: S3ClientApiFailingMock{}}
+
+	r, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{NumberOfThreads: 1})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+
+	if len(r.BucketsStats) != 2 {
+		t.Fatalf("Expecting %v, got %v", 2, len(r.BucketsStats))
+	}
+
+	if r.BucketsStats[0].Error == nil || r.BucketsStats[0].Error.Category != s3stats.ErrorAccessDenied {
+		t.Errorf("Expecting %v, got %+v", s3stats.ErrorAccessDenied, r.BucketsStats[0].Error)
+	}
+
+	if r.BucketsStats[1].Error != nil || r.BucketsStats[1].TotalFiles != 10 {
+		t.Errorf("Expecting stats for %v, got %+v", r.BucketsStats[1].Name, r.BucketsStats[1])
+	}
+
+	if r.Summary.FailedBuckets != 1 || r.Summary.SucceededBuckets != 1 {
+		t.Errorf("Expecting 1 failed and 1 succeeded bucket, got %+v", r.Summary)
+	}
+}
+
+func TestGenerateBucketStatsIsReentrant(t *testing.T) {
+	s3s := s3stats.S3Stats{Api: S3ClientApiMock{}}
+	wantNames := []string{"bucket1", "bucket2"}
+
+	check := func(r s3stats.GenerateBucketStatsOutput) error {
+		if len(r.BucketsStats) != len(wantNames) {
+			return fmt.Errorf("Expecting %v, got %v", len(wantNames), len(r.BucketsStats))
+		}
+		for i, n := range wantNames {
+			if r.BucketsStats[i].Name != n {
+				return fmt.Errorf("Expecting %v, got %v", n, r.BucketsStats[i].Name)
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		r, _ := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{NumberOfThreads: 2})
+		if err := check(r); err != nil {
+			t.Errorf("Back-to-back run %v: %v", i, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, _ := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{NumberOfThreads: 2})
+			errs <- check(r)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent run: %v", err)
+		}
+	}
+}
+
+type S3ClientApiSlowMock struct {
+	S3ClientApiMock
+}
+
+func (s3c S3ClientApiSlowMock) GetObjectStats(c context.Context, params *s3client.ObjectStatsInput) (*s3client.ObjectStatsOutput, error) {
+	<-c.Done()
+	return nil, c.Err()
+}
+
+func TestGenerateBucketStatsCancellation(t *testing.T) {
+	s3s := s3stats.S3Stats{Api: S3ClientApiMock{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, err := s3s.GenerateBucketStats(ctx, &s3stats.GenerateBucketStatsInput{NumberOfThreads: 1})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+	if !r.Incomplete {
+		t.Errorf("Expecting incomplete output")
+	}
+	if r.Summary.SkippedBuckets != 2 || r.Summary.TotalBuckets != 2 {
+		t.Errorf("Expecting 2 skipped buckets out of 2, got %+v", r.Summary)
+	}
+	if len(r.BucketsStats) != 0 {
+		t.Errorf("Expecting %v, got %v", 0, len(r.BucketsStats))
+	}
+}
+
+func TestGenerateBucketStatsBucketTimeout(t *testing.T) {
+	s3s := s3stats.S3Stats{Api: S3ClientApiSlowMock{}}
+
+	r, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 2,
+		BucketTimeout:   10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+	if r.Incomplete {
+		t.Errorf("Expecting complete output, bucket timeouts are failures")
+	}
+	if r.Summary.FailuresByCategory[s3stats.ErrorTimeout] != 2 {
+		t.Errorf("Expecting 2 timeouts, got %+v", r.Summary)
+	}
+}
+
+type S3ClientApiRecordingMock struct {
+	S3ClientApiMock
+	lock   *sync.Mutex
+	listed *[]string
+}
+
+func (s3c S3ClientApiRecordingMock) GetObjectStats(c context.Context, params *s3client.ObjectStatsInput) (*s3client.ObjectStatsOutput, error) {
+	s3c.lock.Lock()
+	*s3c.listed = append(*s3c.listed, params.BucketName)
+	s3c.lock.Unlock()
+	return s3c.S3ClientApiMock.GetObjectStats(c, params)
+}
+
+func TestGenerateBucketStatsResume(t *testing.T) {
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	s3s := s3stats.S3Stats{Api: S3ClientApiFailingMock{}}
+	_, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 2, CheckpointFile: checkpointFile})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+
+	cp, err := s3stats.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Expecting checkpoint to be saved, got %v", err)
+	}
+	if len(cp.Completed) != 1 || cp.Completed[0].Name != "bucket2" {
+		t.Fatalf("Expecting only bucket2 completed, got %+v", cp.Completed)
+	}
+
+	_, err = s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 2, CheckpointFile: checkpointFile, Resume: true, TopN: 10})
+	if err == nil {
+		t.Errorf("Expecting error when resuming with different options")
+	}
+
+	var listed []string
+	s3s = s3stats.S3Stats{Api: S3ClientApiRecordingMock{lock: &sync.Mutex{}, listed: &listed}}
+	r, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 1, CheckpointFile: checkpointFile, Resume: true})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+	if len(listed) != 1 || listed[0] != "bucket1" {
+		t.Errorf("Expecting only bucket1 to be listed, got %v", listed)
+	}
+	if r.Summary.SucceededBuckets != 2 || len(r.BucketsStats) != 2 {
+		t.Errorf("Expecting 2 succeeded buckets, got %+v", r.Summary)
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("Expecting checkpoint to be removed after a complete run, got %v", err)
+	}
+}
+
+func TestGenerateBucketStatsResumeCancellation(t *testing.T) {
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	s3s := s3stats.S3Stats{Api: S3ClientApiFailingMock{}}
+	_, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 2, CheckpointFile: checkpointFile})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s3s = s3stats.S3Stats{Api: S3ClientApiMock{}}
+	r, err := s3s.GenerateBucketStats(ctx, &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads: 1, CheckpointFile: checkpointFile, Resume: true})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+	if !r.Incomplete {
+		t.Errorf("Expecting incomplete output")
+	}
+	if r.Summary.SkippedBuckets != 1 || r.Summary.TotalBuckets != 2 {
+		t.Errorf("Expecting 1 skipped bucket out of 2, got %+v", r.Summary)
+	}
+	if len(r.BucketsStats) != 1 || r.BucketsStats[0].Name != "bucket2" {
+		t.Errorf("Expecting the bucket completed in the checkpoint, got %+v", r.BucketsStats)
+	}
+
+	cp, err := s3stats.LoadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Expecting checkpoint to be saved, got %v", err)
+	}
+	if len(cp.Completed) != 1 || cp.Completed[0].Name != "bucket2" {
+		t.Errorf("Expecting bucket2 to stay completed, got %+v", cp.Completed)
+	}
+}
+
+func TestGenerateBucketStatsFilters(t *testing.T) {
+	modifiedAfter := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s3s := s3stats.S3Stats{Api: S3ClientApiMock{}}
+	r, err := s3s.GenerateBucketStats(context.TODO(), &s3stats.GenerateBucketStatsInput{
+		NumberOfThreads:      1,
+		FilterObjectPrefixes: []string{"logs/", "data/"},
+		ModifiedAfter:        modifiedAfter,
+		MinSize:              1024,
+		KeyPattern:           `\.parquet$`,
+		StorageClasses:       []string{"STANDARD"},
+	})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+
+	f := r.Filters
+	if len(f.ObjectPrefixes) != 2 || f.MinSize != 1024 || f.KeyPattern != `\.parquet$` || len(f.StorageClasses) != 1 {
+		t.Errorf("Expecting the object filters of the run, got %+v", f)
+	}
+	if f.ModifiedAfter == nil || !f.ModifiedAfter.Equal(modifiedAfter) {
+		t.Errorf("Expecting %v, got %v", modifiedAfter, f.ModifiedAfter)
+	}
+	if f.ModifiedBefore != nil || f.CreatedAfter != nil || f.MaxSize != 0 {
+		t.Errorf("Expecting unset filters to be omitted, got %+v", f)
+	}
+	if len(f.Notes) != 0 {
+		t.Errorf("Expecting no notes without version and multipart stats, got %v", f.Notes)
+	}
+
+	f = s3stats.NewFilters(&s3stats.GenerateBucketStatsInput{GetVersionStats: true, GetMultipartUploads: true, MinSize: 1024})
+	if len(f.Notes) != 2 {
+		t.Errorf("Expecting notes for versions and multipart uploads, got %v", f.Notes)
+	}
 }
@@ -0,0 +1,133 @@
+package s3client
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RegexPrefix marks a bucket name pattern as a regular expression. Patterns
+// without it are globs, e.g. prod-* or *-logs.
+const RegexPrefix = "regex:"
+
+// NamePattern matches bucket names against a glob or a regular expression.
+type NamePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func ParseNamePattern(p string) (*NamePattern, error) {
+	if strings.HasPrefix(p, RegexPrefix) {
+		re, err := regexp.Compile(strings.TrimPrefix(p, RegexPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return &NamePattern{re: re}, nil
+	}
+
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, err
+	}
+	return &NamePattern{glob: p}, nil
+}
+
+func (np *NamePattern) Match(name string) bool {
+	if np.re != nil {
+		return np.re.MatchString(name)
+	}
+	ok, _ := path.Match(np.glob, name)
+	return ok
+}
+
+func parseNamePatterns(ps []string) ([]*NamePattern, error) {
+	var r []*NamePattern
+	for _, p := range ps {
+		np, err := ParseNamePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, np)
+	}
+	return r, nil
+}
+
+func matchAny(name string, nps []*NamePattern) bool {
+	for _, np := range nps {
+		if np.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketFilter selects buckets by name and creation date, as set in AllBucketsInput.
+type bucketFilter struct {
+	contains      string
+	include       []*NamePattern
+	exclude       []*NamePattern
+	names         map[string]bool
+	createdAfter  time.Time
+	createdBefore time.Time
+}
+
+func newBucketFilter(params *AllBucketsInput) (*bucketFilter, error) {
+	bf := &bucketFilter{
+		contains:      params.FilterBucketName,
+		createdAfter:  params.CreatedAfter,
+		createdBefore: params.CreatedBefore,
+	}
+
+	var err error
+	if bf.include, err = parseNamePatterns(params.IncludeBuckets); err != nil {
+		return nil, err
+	}
+	if bf.exclude, err = parseNamePatterns(params.ExcludeBuckets); err != nil {
+		return nil, err
+	}
+
+	if len(params.BucketNames) > 0 {
+		bf.names = map[string]bool{}
+		for _, n := range params.BucketNames {
+			bf.names[n] = false
+		}
+	}
+
+	return bf, nil
+}
+
+func (bf *bucketFilter) match(b Bucket) bool {
+	if bf.contains != "" && !strings.Contains(b.Name, bf.contains) {
+		return false
+	}
+	if bf.names != nil {
+		if _, ok := bf.names[b.Name]; !ok {
+			return false
+		}
+		bf.names[b.Name] = true
+	}
+	if len(bf.include) > 0 && !matchAny(b.Name, bf.include) {
+		return false
+	}
+	if matchAny(b.Name, bf.exclude) {
+		return false
+	}
+	if !bf.createdAfter.IsZero() && b.CreationDate.Before(bf.createdAfter) {
+		return false
+	}
+	if !bf.createdBefore.IsZero() && !b.CreationDate.Before(bf.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// warnMissing logs the buckets of the explicit list that were not listed.
+func (bf *bucketFilter) warnMissing() {
+	for n, found := range bf.names {
+		if !found {
+			log.Warnf("Bucket %v of the bucket list was not found", n)
+		}
+	}
+}
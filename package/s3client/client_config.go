@@ -0,0 +1,149 @@
+package s3client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// ClientConfig sets how the client connects to S3 or to S3 compatible services
+// such as MinIO, Ceph RGW or localstack, and the credentials it uses. The zero
+// value uses the AWS endpoints and the region and default credential chain of
+// the environment.
+type ClientConfig struct {
+	// Endpoint is the URL of the service, e.g. https://minio.local:9000.
+	Endpoint string
+	// UsePathStyle addresses buckets in the path of the URL instead of in the
+	// hostname, as most S3 compatible services require.
+	UsePathStyle bool
+	// Region overrides the region of the environment. Buckets whose location
+	// cannot be looked up are accessed in this region.
+	Region string
+	// CABundle is a PEM file with the certificates trusted besides those of the system.
+	CABundle string
+	// InsecureSkipVerify disables the verification of the certificate of the service.
+	InsecureSkipVerify bool
+	// Profile selects a profile of the AWS shared config and credentials files.
+	Profile string
+	// RoleARN is a role assumed with the credentials of the profile, or with the
+	// token of WebIdentityTokenFile when it is set.
+	RoleARN string
+	// ExternalID is passed when assuming RoleARN, as required by the trust policy
+	// of some cross account roles. It cannot be set with WebIdentityTokenFile.
+	ExternalID string
+	// RoleSessionName names the session of RoleARN, as shown in CloudTrail.
+	RoleSessionName string
+	// WebIdentityTokenFile is a file with an OIDC token exchanged for the
+	// credentials of RoleARN, e.g. the token of an EKS service account.
+	WebIdentityTokenFile string
+}
+
+func loadConfig(c context.Context, cc ClientConfig) (aws.Config, error) {
+	if cc.RoleARN == "" && (cc.ExternalID != "" || cc.RoleSessionName != "" || cc.WebIdentityTokenFile != "") {
+		return aws.Config{}, errors.New("a role ARN is required to set an external ID, a session name or a web identity token file")
+	}
+	if cc.ExternalID != "" && cc.WebIdentityTokenFile != "" {
+		return aws.Config{}, errors.New("an external ID cannot be set with a web identity token file, roles assumed with a web identity take no external ID")
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if cc.Region != "" {
+		opts = append(opts, config.WithRegion(cc.Region))
+	}
+	if cc.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(cc.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(c, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if cfg.Region == "" && cc.Endpoint != "" {
+		cfg.Region = defaultRegion
+	}
+
+	if p := roleCredentials(cfg, cc); p != nil {
+		cfg.Credentials = aws.NewCredentialsCache(p)
+	}
+	return cfg, nil
+}
+
+// roleCredentials returns the provider of the credentials of the role of cc, or
+// nil when no role is set and the credentials of cfg are used.
+func roleCredentials(cfg aws.Config, cc ClientConfig) aws.CredentialsProvider {
+	if cc.RoleARN == "" {
+		return nil
+	}
+
+	stsClient := sts.NewFromConfig(cfg)
+	if cc.WebIdentityTokenFile != "" {
+		return stscreds.NewWebIdentityRoleProvider(stsClient, cc.RoleARN, stscreds.IdentityTokenFile(cc.WebIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = cc.RoleSessionName
+			})
+	}
+
+	return stscreds.NewAssumeRoleProvider(stsClient, cc.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = cc.RoleSessionName
+		if cc.ExternalID != "" {
+			o.ExternalID = aws.String(cc.ExternalID)
+		}
+	})
+}
+
+// s3Options returns the options of the S3 API client set by cc.
+func s3Options(cc ClientConfig) (func(*s3.Options), error) {
+	var hc *awshttp.BuildableClient
+	if cc.CABundle != "" || cc.InsecureSkipVerify {
+		tc, err := tlsConfig(cc)
+		if err != nil {
+			return nil, err
+		}
+		hc = awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			tr.TLSClientConfig = tc
+		})
+	}
+
+	return func(o *s3.Options) {
+		o.UsePathStyle = cc.UsePathStyle
+		if cc.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFunc(func(region string, _ s3.EndpointResolverOptions) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: cc.Endpoint, SigningRegion: region}, nil
+			})
+		}
+		if hc != nil {
+			o.HTTPClient = hc
+		}
+	}, nil
+}
+
+func tlsConfig(cc ClientConfig) (*tls.Config, error) {
+	tc := &tls.Config{InsecureSkipVerify: cc.InsecureSkipVerify}
+	if cc.CABundle == "" {
+		return tc, nil
+	}
+
+	pem, err := ioutil.ReadFile(cc.CABundle)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA bundle " + cc.CABundle)
+	}
+	tc.RootCAs = pool
+	return tc, nil
+}
@@ -0,0 +1,151 @@
+package s3client
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectFilter selects the objects accounted by GetObjectStats. Each condition
+// set must match and zero values disable them. ModifiedAfter and MinSize are
+// inclusive, ModifiedBefore is exclusive and MaxSize is inclusive. An object
+// matches Suffixes when its key ends with any of them and KeyPattern is a
+// regular expression matched against the key.
+type ObjectFilter struct {
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	MinSize        int64
+	MaxSize        int64
+	Suffixes       []string
+	KeyPattern     string
+	StorageClasses []string
+}
+
+type objectMatcher struct {
+	filter         ObjectFilter
+	keyPattern     *regexp.Regexp
+	storageClasses map[string]bool
+}
+
+// newObjectMatcher returns nil when the filter has no condition set, matching
+// every object.
+func newObjectMatcher(f ObjectFilter) (*objectMatcher, error) {
+	m := &objectMatcher{filter: f}
+	set := !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() || f.MinSize > 0 || f.MaxSize > 0 ||
+		len(f.Suffixes) > 0
+
+	if f.KeyPattern != "" {
+		re, err := regexp.Compile(f.KeyPattern)
+		if err != nil {
+			return nil, err
+		}
+		m.keyPattern = re
+		set = true
+	}
+
+	if len(f.StorageClasses) > 0 {
+		m.storageClasses = map[string]bool{}
+		for _, sc := range f.StorageClasses {
+			m.storageClasses[strings.ToUpper(sc)] = true
+		}
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return m, nil
+}
+
+func (m *objectMatcher) match(o types.Object) bool {
+	return m.matchKey(*o.Key) && m.matchModified(*o.LastModified) && m.matchSize(o.Size) &&
+		m.matchStorageClass(string(o.StorageClass))
+}
+
+// matchVersion matches an object version as an object.
+func (m *objectMatcher) matchVersion(v types.ObjectVersion) bool {
+	return m.matchKey(*v.Key) && m.matchModified(*v.LastModified) && m.matchSize(v.Size) &&
+		m.matchStorageClass(string(v.StorageClass))
+}
+
+// matchDeleteMarker matches the key and modified date of a delete marker, which
+// has no size nor storage class.
+func (m *objectMatcher) matchDeleteMarker(d types.DeleteMarkerEntry) bool {
+	return m.matchKey(*d.Key) && m.matchModified(*d.LastModified)
+}
+
+// matchUpload matches the key, the storage class and the initiation date, as
+// modified date, of a multipart upload. Its size is only known once its parts
+// are listed and is matched with matchSize.
+func (m *objectMatcher) matchUpload(u types.MultipartUpload) bool {
+	return m.matchKey(*u.Key) && m.matchModified(*u.Initiated) && m.matchStorageClass(string(u.StorageClass))
+}
+
+func (m *objectMatcher) matchKey(key string) bool {
+	if m == nil {
+		return true
+	}
+	if len(m.filter.Suffixes) > 0 && !hasAnySuffix(key, m.filter.Suffixes) {
+		return false
+	}
+	return m.keyPattern == nil || m.keyPattern.MatchString(key)
+}
+
+func (m *objectMatcher) matchModified(d time.Time) bool {
+	if m == nil {
+		return true
+	}
+	f := m.filter
+	if !f.ModifiedAfter.IsZero() && d.Before(f.ModifiedAfter) {
+		return false
+	}
+	return f.ModifiedBefore.IsZero() || d.Before(f.ModifiedBefore)
+}
+
+func (m *objectMatcher) matchSize(size int64) bool {
+	if m == nil {
+		return true
+	}
+	return size >= m.filter.MinSize && (m.filter.MaxSize <= 0 || size <= m.filter.MaxSize)
+}
+
+func (m *objectMatcher) matchStorageClass(sc string) bool {
+	if m == nil || m.storageClasses == nil {
+		return true
+	}
+	if sc == "" {
+		sc = string(types.ObjectStorageClassStandard)
+	}
+	return m.storageClasses[sc]
+}
+
+func hasAnySuffix(key string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectPrefixes returns the prefixes to list, dropping those under another
+// prefix of the list so no object is listed twice. No prefix lists the bucket.
+func objectPrefixes(prefix string, prefixes []string) []string {
+	ps := append([]string{prefix}, prefixes...)
+	if prefix == "" && len(prefixes) > 0 {
+		ps = append([]string{}, prefixes...)
+	}
+	sort.Strings(ps)
+
+	var r []string
+	for _, p := range ps {
+		if len(r) > 0 && strings.HasPrefix(p, r[len(r)-1]) {
+			continue
+		}
+		r = append(r, p)
+	}
+	return r
+}
@@ -0,0 +1,154 @@
+package s3client
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/elribeiro/s3-stats-tool/internal/comparedate"
+)
+
+const DefaultDelimiter = "/"
+
+type PrefixStats struct {
+	Prefix                     string
+	TotalFiles                 int64
+	SizeInBytes                int64
+	MostRecentFileModifiedDate time.Time
+	Prefixes                   []PrefixStats
+}
+
+type prefixAggregate struct {
+	stats  PrefixStats
+	parent string
+}
+
+// prefixAggregator keeps a flat index of every prefix seen up to depth levels
+// below basePrefix, so each object is accounted in O(depth). Keys are split with
+// the delimiter as they are listed instead of listing each level with a
+// Delimiter: the bucket is listed once whatever the depth, and shards listed in
+// parallel or resumed from a checkpoint aggregate into the same prefixes.
+type prefixAggregator struct {
+	basePrefix string
+	delimiter  string
+	depth      int
+	prefixes   map[string]*prefixAggregate
+}
+
+func newPrefixAggregator(basePrefix, delimiter string, depth int) *prefixAggregator {
+	if delimiter == "" {
+		delimiter = DefaultDelimiter
+	}
+	return &prefixAggregator{
+		basePrefix: basePrefix,
+		delimiter:  delimiter,
+		depth:      depth,
+		prefixes:   map[string]*prefixAggregate{},
+	}
+}
+
+func (pa *prefixAggregator) add(o types.Object) {
+	parts := strings.SplitN(strings.TrimPrefix(*o.Key, pa.basePrefix), pa.delimiter, pa.depth+1)
+
+	parent := ""
+	p := pa.basePrefix
+	for i := 0; i < len(parts)-1; i++ {
+		p += parts[i] + pa.delimiter
+
+		agg, ok := pa.prefixes[p]
+		if !ok {
+			agg = &prefixAggregate{stats: PrefixStats{Prefix: p}, parent: parent}
+			pa.prefixes[p] = agg
+		}
+		agg.stats.TotalFiles += 1
+		agg.stats.SizeInBytes += o.Size
+		agg.stats.MostRecentFileModifiedDate = *comparedate.GetMostRecentDate(&agg.stats.MostRecentFileModifiedDate, o.LastModified)
+
+		parent = p
+	}
+}
+
+// merge adds the prefixes aggregated by another aggregator with the same
+// base prefix, delimiter and depth.
+func (pa *prefixAggregator) merge(o *prefixAggregator) {
+	for p, oagg := range o.prefixes {
+		agg, ok := pa.prefixes[p]
+		if !ok {
+			pa.prefixes[p] = &prefixAggregate{stats: oagg.stats, parent: oagg.parent}
+			continue
+		}
+		agg.stats.TotalFiles += oagg.stats.TotalFiles
+		agg.stats.SizeInBytes += oagg.stats.SizeInBytes
+		agg.stats.MostRecentFileModifiedDate = *comparedate.GetMostRecentDate(&agg.stats.MostRecentFileModifiedDate, &oagg.stats.MostRecentFileModifiedDate)
+	}
+}
+
+// flat returns the aggregated prefixes without nesting, sorted by prefix.
+func (pa *prefixAggregator) flat() []PrefixStats {
+	var r []PrefixStats
+	for _, agg := range pa.prefixes {
+		r = append(r, agg.stats)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Prefix < r[j].Prefix })
+	return r
+}
+
+// leaves returns the aggregated prefixes that have no child prefix, i.e. the
+// deepest prefix reached by each branch, sorted by prefix. No leaf contains
+// another, so their sizes never count the same objects twice.
+func (pa *prefixAggregator) leaves() []PrefixStats {
+	parents := map[string]bool{}
+	for _, agg := range pa.prefixes {
+		parents[agg.parent] = true
+	}
+
+	var r []PrefixStats
+	for p, agg := range pa.prefixes {
+		if !parents[p] {
+			r = append(r, agg.stats)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Prefix < r[j].Prefix })
+	return r
+}
+
+// restore rebuilds the index from the prefixes returned by flat.
+func (pa *prefixAggregator) restore(pss []PrefixStats) {
+	for _, ps := range pss {
+		pa.prefixes[ps.Prefix] = &prefixAggregate{stats: ps, parent: pa.parentOf(ps.Prefix)}
+	}
+}
+
+func (pa *prefixAggregator) parentOf(p string) string {
+	rel := strings.TrimSuffix(strings.TrimPrefix(p, pa.basePrefix), pa.delimiter)
+	i := strings.LastIndex(rel, pa.delimiter)
+	if i < 0 {
+		return ""
+	}
+	return pa.basePrefix + rel[:i+len(pa.delimiter)]
+}
+
+// tree returns the aggregated prefixes nested by parent, sorted by prefix.
+func (pa *prefixAggregator) tree() []PrefixStats {
+	children := map[string][]string{}
+	for p, agg := range pa.prefixes {
+		children[agg.parent] = append(children[agg.parent], p)
+	}
+
+	var build func(parent string) []PrefixStats
+	build = func(parent string) []PrefixStats {
+		ps := children[parent]
+		sort.Strings(ps)
+
+		var r []PrefixStats
+		for _, p := range ps {
+			s := pa.prefixes[p].stats
+			s.Prefixes = build(p)
+			r = append(r, s)
+		}
+		return r
+	}
+
+	return build("")
+}
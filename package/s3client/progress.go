@@ -0,0 +1,225 @@
+package s3client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PartialObjectStats is the serializable state of the stats of a listing that
+// has not finished yet.
+type PartialObjectStats struct {
+	TotalFiles                 int64
+	SizeInBytes                int64
+	MostRecentFile             string
+	MostRecentFileModifiedDate time.Time
+	OldestFile                 string
+	OldestFileModifiedDate     time.Time
+	LargestFile                string
+	LargestFileSizeInBytes     int64
+	SmallestFile               string
+	SmallestFileSizeInBytes    int64
+	StorageClasses             []StorageClass
+	SizeDistribution           []SizeRange
+	AgeDistribution            []AgeRange
+	Prefixes                   []PrefixStats
+	LargestFiles               []ObjectInfo
+}
+
+// ShardProgress is the state of the listing of one shard of a bucket. StartAfter
+// is the last key listed, from which the listing is resumed.
+type ShardProgress struct {
+	Prefix     string
+	StartAfter string
+	EndAt      string
+	Done       bool
+	Stats      PartialObjectStats
+}
+
+// ObjectStatsProgress is a snapshot of a GetObjectStats call in progress. It is
+// resumed by passing it as ObjectStatsInput.Resume along with the same input.
+type ObjectStatsProgress struct {
+	ReferenceDate time.Time
+	Base          PartialObjectStats
+	Shards        []ShardProgress
+}
+
+// Progress tracks the listing of a bucket so it can be snapshotted while
+// GetObjectStats is running and after it returned with an error.
+type Progress struct {
+	lock          sync.Mutex
+	started       bool
+	referenceDate time.Time
+	base          *ObjectStatsOutput
+	shards        []*shardState
+}
+
+type shardState struct {
+	shard objectShard
+	done  bool
+	stats *ObjectStatsOutput
+}
+
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// Snapshot returns the state of the listing, or false when the shards of the
+// bucket were not discovered yet and there is nothing to resume from.
+func (p *Progress) Snapshot() (ObjectStatsProgress, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.started {
+		return ObjectStatsProgress{}, false
+	}
+
+	r := ObjectStatsProgress{
+		ReferenceDate: p.referenceDate,
+		Base:          p.base.partial(),
+	}
+	for _, st := range p.shards {
+		r.Shards = append(r.Shards, ShardProgress{
+			Prefix:     st.shard.prefix,
+			StartAfter: st.shard.startAfter,
+			EndAt:      st.shard.endAt,
+			Done:       st.done,
+			Stats:      st.stats.partial(),
+		})
+	}
+	return r, true
+}
+
+func (p *Progress) start(params *ObjectStatsInput, base *ObjectStatsOutput, shards []objectShard) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.started = true
+	p.referenceDate = params.ReferenceDate
+	p.base = base
+	p.shards = nil
+	for _, sh := range shards {
+		p.shards = append(p.shards, &shardState{shard: sh, stats: newObjectStatsOutput(params)})
+	}
+}
+
+func (p *Progress) restore(params *ObjectStatsInput, r *ObjectStatsProgress) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.started = true
+	p.referenceDate = r.ReferenceDate
+	p.base = restoreObjectStatsOutput(params, r.Base)
+	p.shards = nil
+	for _, sp := range r.Shards {
+		p.shards = append(p.shards, &shardState{
+			shard: objectShard{prefix: sp.Prefix, startAfter: sp.StartAfter, endAt: sp.EndAt},
+			done:  sp.Done,
+			stats: restoreObjectStatsOutput(params, sp.Stats),
+		})
+	}
+}
+
+func (p *Progress) pending() []*shardState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var r []*shardState
+	for _, st := range p.shards {
+		if !st.done {
+			r = append(r, st)
+		}
+	}
+	return r
+}
+
+// addPage adds the objects of a page of a shard listing that match m, reporting
+// whether the shard is done because the page went past its upper bound.
+func (p *Progress) addPage(st *shardState, objects []types.Object, m *objectMatcher) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, o := range objects {
+		if !st.shard.includes(*o.Key) {
+			st.done = true
+			return true
+		}
+		if m.match(o) {
+			st.stats.addObject(o)
+		}
+		st.shard.startAfter = *o.Key
+	}
+	return false
+}
+
+func (p *Progress) finish(st *shardState) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	st.done = true
+}
+
+// result merges the partial aggregates of the base listing and every shard into
+// a new ObjectStatsOutput, leaving the tracked state untouched.
+func (p *Progress) result(params *ObjectStatsInput) *ObjectStatsOutput {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	bs := newObjectStatsOutput(params)
+	bs.merge(p.base)
+	for _, st := range p.shards {
+		bs.merge(st.stats)
+	}
+	bs.finalize()
+
+	return bs
+}
+
+func (bs *ObjectStatsOutput) partial() PartialObjectStats {
+	r := PartialObjectStats{
+		TotalFiles:                 bs.TotalFiles,
+		SizeInBytes:                bs.SizeInBytes,
+		MostRecentFile:             bs.MostRecentFile,
+		MostRecentFileModifiedDate: bs.MostRecentFileModifiedDate,
+		OldestFile:                 bs.OldestFile,
+		OldestFileModifiedDate:     bs.OldestFileModifiedDate,
+		LargestFile:                bs.LargestFile,
+		LargestFileSizeInBytes:     bs.LargestFileSizeInBytes,
+		SmallestFile:               bs.SmallestFile,
+		SmallestFileSizeInBytes:    bs.SmallestFileSizeInBytes,
+		StorageClasses:             append([]StorageClass{}, bs.StorageClasses...),
+		SizeDistribution:           append([]SizeRange{}, bs.SizeDistribution...),
+		AgeDistribution:            append([]AgeRange{}, bs.AgeDistribution...),
+	}
+	if bs.prefixes != nil {
+		r.Prefixes = bs.prefixes.flat()
+	}
+	if bs.largestFiles != nil {
+		r.LargestFiles = append([]ObjectInfo{}, bs.largestFiles.heap...)
+	}
+	return r
+}
+
+func restoreObjectStatsOutput(params *ObjectStatsInput, ps PartialObjectStats) *ObjectStatsOutput {
+	bs := newObjectStatsOutput(params)
+	bs.TotalFiles = ps.TotalFiles
+	bs.SizeInBytes = ps.SizeInBytes
+	bs.MostRecentFile, bs.MostRecentFileModifiedDate = ps.MostRecentFile, ps.MostRecentFileModifiedDate
+	bs.OldestFile, bs.OldestFileModifiedDate = ps.OldestFile, ps.OldestFileModifiedDate
+	bs.LargestFile, bs.LargestFileSizeInBytes = ps.LargestFile, ps.LargestFileSizeInBytes
+	bs.SmallestFile, bs.SmallestFileSizeInBytes = ps.SmallestFile, ps.SmallestFileSizeInBytes
+	bs.StorageClasses = append([]StorageClass{}, ps.StorageClasses...)
+	copy(bs.SizeDistribution, ps.SizeDistribution)
+	copy(bs.AgeDistribution, ps.AgeDistribution)
+
+	if bs.prefixes != nil {
+		bs.prefixes.restore(ps.Prefixes)
+	}
+	if bs.largestFiles != nil {
+		for _, oi := range ps.LargestFiles {
+			bs.largestFiles.push(oi)
+		}
+	}
+	return bs
+}
@@ -0,0 +1,186 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sync"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// regionLookups bounds the concurrent location lookups of GetAllBuckets.
+const regionLookups = 8
+
+// defaultRegion is the region of the buckets without location constraint and of
+// the clients of S3 compatible services without a region set.
+const defaultRegion = "us-east-1"
+
+// unsupportedLocationCodes are returned by S3 compatible services that do not
+// implement GetBucketLocation.
+var unsupportedLocationCodes = map[string]bool{
+	"NotImplemented":   true,
+	"MethodNotAllowed": true,
+	"XNotImplemented":  true,
+}
+
+// RegionCache keeps the region of each bucket, so the location of a bucket is
+// looked up once for every operation on it. It is safe for concurrent use.
+type RegionCache struct {
+	lock    sync.RWMutex
+	regions map[string]string
+}
+
+func NewRegionCache() *RegionCache {
+	return &RegionCache{regions: map[string]string{}}
+}
+
+func (rc *RegionCache) get(bucketName string) (string, bool) {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+
+	r, ok := rc.regions[bucketName]
+	return r, ok
+}
+
+func (rc *RegionCache) set(bucketName, region string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.regions[bucketName] = region
+}
+
+// NormalizeRegion maps the location constraint returned by GetBucketLocation to
+// a region. Buckets in us-east-1 have no constraint and legacy buckets in
+// eu-west-1 may have EU as constraint.
+func NormalizeRegion(lc types.BucketLocationConstraint) string {
+	switch lc {
+	case "":
+		return defaultRegion
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1"
+	}
+	return string(lc)
+}
+
+// BucketRegion returns the region of a bucket, looked up only once per bucket
+// when the client has a RegionCache. Services that do not implement
+// GetBucketLocation get the region of the client.
+func (s3c S3Client) BucketRegion(c context.Context, bucketName string) (string, error) {
+	if s3c.Regions != nil {
+		if r, ok := s3c.Regions.get(bucketName); ok {
+			return r, nil
+		}
+	}
+
+	var r string
+	loc, err := s3c.Api.GetBucketLocation(c, &s3.GetBucketLocationInput{Bucket: &bucketName})
+	switch {
+	case err == nil:
+		r = NormalizeRegion(loc.LocationConstraint)
+	case unsupportedLocation(err):
+		log.Debugf("Bucket location of %v not supported, using the region of the client: %v", bucketName, err)
+		r = s3c.Region
+		if r == "" {
+			r = defaultRegion
+		}
+	default:
+		log.Error("Bucket location not found ", err)
+		return "", err
+	}
+
+	if s3c.Regions != nil {
+		s3c.Regions.set(bucketName, r)
+	}
+	return r, nil
+}
+
+func unsupportedLocation(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) && unsupportedLocationCodes[ae.ErrorCode()] {
+		return true
+	}
+
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		switch re.Response.StatusCode {
+		case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return true
+		}
+	}
+	return false
+}
+
+// regionOption returns the option that sends a request to the region of the bucket.
+func (s3c S3Client) regionOption(c context.Context, bucketName string) (func(*s3.Options), error) {
+	r, err := s3c.BucketRegion(c, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	return func(o *s3.Options) { o.Region = r }, nil
+}
+
+// resolveRegions sets the region of every bucket, leaving it empty for the
+// buckets whose location cannot be read so their own operations report the error.
+func (s3c S3Client) resolveRegions(c context.Context, bs []Bucket) {
+	sem := NewSemaphore(regionLookups)
+	var wg sync.WaitGroup
+	for i := range bs {
+		if sem.Acquire(c) != nil {
+			break
+		}
+		wg.Add(1)
+		go func(b *Bucket) {
+			defer wg.Done()
+			defer sem.Release()
+			r, err := s3c.BucketRegion(c, b.Name)
+			if err != nil {
+				log.Warnf("Region of bucket %v not resolved: %v", b.Name, err)
+				return
+			}
+			b.Region = r
+		}(&bs[i])
+	}
+	wg.Wait()
+}
+
+// filterRegions keeps the buckets whose region matches any include pattern, or
+// every bucket when there is none, and no exclude pattern. Patterns are globs
+// such as eu-*. Buckets whose region was not resolved never match a pattern, and
+// a warning is logged when one is skipped for that reason.
+func filterRegions(bs []Bucket, include, exclude []string) []Bucket {
+	var r []Bucket
+	for _, b := range bs {
+		if len(include) > 0 && b.Region == "" {
+			log.Warnf("Skipping bucket %v whose region was not resolved, so it matches no included region", b.Name)
+			continue
+		}
+		if len(include) > 0 && !matchRegion(b.Region, include) {
+			log.Debugf("Skipping bucket %v in region %v not included", b.Name, b.Region)
+			continue
+		}
+		if matchRegion(b.Region, exclude) {
+			log.Debugf("Skipping bucket %v in excluded region %v", b.Name, b.Region)
+			continue
+		}
+		r = append(r, b)
+	}
+	return r
+}
+
+func matchRegion(region string, patterns []string) bool {
+	if region == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, region); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -3,14 +3,18 @@ package s3client
 import (
 	"context"
 	"errors"
-	"strings"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/elribeiro/s3-stats-tool/internal/bytesize"
 	"github.com/elribeiro/s3-stats-tool/internal/comparedate"
+	"github.com/elribeiro/s3-stats-tool/internal/comparesize"
+	"github.com/elribeiro/s3-stats-tool/internal/policy"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -29,38 +33,243 @@ type S3AwsClientApi interface {
 
 	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput,
 		optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput,
+		optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+
+	ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput,
+		optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+
+	ListParts(ctx context.Context, params *s3.ListPartsInput,
+		optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+
+	GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+
+	GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+
+	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+
+	GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput,
+		optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
+
+	GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error)
+
+	GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+
+	GetBucketAcl(ctx context.Context, params *s3.GetBucketAclInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketAclOutput, error)
 }
 
+// S3Client collects the stats through Api. Region is used for the buckets whose
+// location cannot be looked up, on services without GetBucketLocation.
 type S3Client struct {
-	Api S3AwsClientApi
+	Api     S3AwsClientApi
+	Regions *RegionCache
+	Region  string
 }
 
+// AllBucketsInput selects the buckets returned by GetAllBuckets. Every filter
+// set must match: FilterBucketName is a substring of the name, BucketNames an
+// explicit list of names, IncludeBuckets and ExcludeBuckets name patterns as
+// parsed by ParseNamePattern, and CreatedAfter and CreatedBefore bound the
+// creation date, inclusive and exclusive respectively.
 type AllBucketsInput struct {
 	FilterBucketName string
+	BucketNames      []string
+	IncludeBuckets   []string
+	ExcludeBuckets   []string
+	CreatedAfter     time.Time
+	CreatedBefore    time.Time
+	IncludeRegions   []string
+	ExcludeRegions   []string
 }
 type Bucket struct {
 	Name         string
 	CreationDate time.Time
+	Region       string
 }
 
 type AllBucketsOutput struct {
 	Buckets []Bucket
 }
 
+// ObjectStatsInput lists the objects under Prefix and every one of Prefixes,
+// accounting only those matching Filter. With more than one prefix, PrefixDepth
+// levels start at the root of the bucket.
 type ObjectStatsInput struct {
-	BucketName string
-	Prefix     string
+	BucketName             string
+	Prefix                 string
+	Prefixes               []string
+	Filter                 ObjectFilter
+	SizeDistributionBounds []int64
+	ReferenceDate          time.Time
+	PrefixDepth            int
+	Delimiter              string
+	TopN                   int
+	ShardMode              string
+	ShardBoundaries        []string
+	Limiter                Semaphore
+	Progress               *Progress
+	Resume                 *ObjectStatsProgress
 }
 
 type ObjectStatsOutput struct {
 	TotalFiles                 int64
+	SizeInBytes                int64
 	SizeInKB                   int64
+	MostRecentFile             string
 	MostRecentFileModifiedDate time.Time
+	OldestFile                 string
+	OldestFileModifiedDate     time.Time
+	LargestFile                string
+	LargestFileSizeInBytes     int64
+	SmallestFile               string
+	SmallestFileSizeInBytes    int64
+	AverageFileSizeInBytes     int64
+	StorageClasses             []StorageClass
+	SizeDistribution           []SizeRange
+	AgeDistribution            []AgeRange
+	Prefixes                   []PrefixStats
+	LargestFiles               []ObjectInfo
+	LargestPrefixes            []PrefixStats
+	referenceDate              time.Time
+	prefixes                   *prefixAggregator
+	largestFiles               *topObjects
+}
+
+type SizeRange struct {
+	Label          string
+	MinSizeInBytes int64
+	MaxSizeInBytes int64
+	TotalFiles     int64
+	SizeInBytes    int64
 }
 
 type StorageClass struct {
-	SizeInKB     int64
 	StorageClass string
+	TotalFiles   int64
+	SizeInBytes  int64
+}
+
+type AgeRange struct {
+	Label        string
+	MinAgeInDays int
+	MaxAgeInDays int
+	TotalFiles   int64
+	SizeInBytes  int64
+}
+
+// ObjectVersionStatsInput accounts only the versions and delete markers that
+// match Filter, as GetObjectStats does with objects. Delete markers are matched by
+// key and modified date only.
+type ObjectVersionStatsInput struct {
+	BucketName string
+	Prefix     string
+	Prefixes   []string
+	Filter     ObjectFilter
+}
+
+type ObjectVersionStatsOutput struct {
+	CurrentFiles                     int64
+	CurrentSizeInBytes               int64
+	NoncurrentFiles                  int64
+	NoncurrentSizeInBytes            int64
+	DeleteMarkers                    int64
+	OldestNoncurrentFile             string
+	OldestNoncurrentFileModifiedDate time.Time
+}
+
+// MultipartUploadStatsInput accounts only the uploads that match Filter, taking
+// the initiation date of an upload as its modified date and the size of its
+// uploaded parts as its size.
+type MultipartUploadStatsInput struct {
+	BucketName string
+	Prefix     string
+	Prefixes   []string
+	Filter     ObjectFilter
+}
+
+type MultipartUpload struct {
+	Key          string
+	UploadID     string
+	Initiated    time.Time
+	StorageClass string
+	TotalParts   int64
+	SizeInBytes  int64
+}
+
+type MultipartUploadStatsOutput struct {
+	TotalUploads                  int64
+	SizeInBytes                   int64
+	Uploads                       []MultipartUpload
+	HasAbortIncompleteUploadsRule bool
+}
+
+type BucketVersioningInfoInput struct {
+	BucketName string
+}
+
+type BucketVersioningInfoOutput struct {
+	Status    string
+	MFADelete string
+}
+
+type BucketObjectLockInfoInput struct {
+	BucketName string
+}
+
+type BucketObjectLockInfoOutput struct {
+	Enabled        bool
+	RetentionMode  string
+	RetentionDays  int32
+	RetentionYears int32
+}
+
+type BucketEncryptionInfoInput struct {
+	BucketName string
+}
+
+type BucketEncryptionInfoOutput struct {
+	Algorithm        string
+	KMSMasterKeyID   string
+	BucketKeyEnabled bool
+}
+
+type BucketPublicAccessInfoInput struct {
+	BucketName string
+}
+
+type BucketPublicAccessInfoOutput struct {
+	PublicAccessBlockConfigured bool
+	BlockPublicAcls             bool
+	IgnorePublicAcls            bool
+	BlockPublicPolicy           bool
+	RestrictPublicBuckets       bool
+	PolicyIsPublic              bool
+	Policy                      string
+	PublicPolicyStatements      []string
+	PublicACLGrants             []string
+	Exposure                    string
+}
+
+// Exposure categories of a bucket. ExposureNotPublic is set when the access could
+// be assessed and nothing makes the bucket public without being blocked.
+const (
+	ExposureBlocked      = "blocked"
+	ExposurePolicyPublic = "policy-public"
+	ExposureACLPublic    = "acl-public"
+	ExposureUnknown      = "unknown"
+	ExposureNotPublic    = "not-public"
+)
+
+var publicGroups = []string{
+	"http://acs.amazonaws.com/groups/global/AllUsers",
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
 }
 
 type BucketReplicationInfoInput struct {
@@ -68,15 +277,33 @@ type BucketReplicationInfoInput struct {
 }
 
 type BucketReplicationRule struct {
-	DestinationBucket  string
-	DestinationAccount string
-	StorageClass       string
-	ID                 string
-	Priority           int32
-	Status             string
+	DestinationBucket            string
+	DestinationAccount           string
+	StorageClass                 string
+	ID                           string
+	Priority                     int32
+	Status                       string
+	Filter                       ReplicationRuleFilter
+	DeleteMarkerReplication      string
+	ExistingObjectReplication    string
+	ReplicaModifications         string
+	SseKmsEncryptedObjects       string
+	ReplicationTimeControl       string
+	ReplicationTimeMinutes       int32
+	Metrics                      string
+	MetricsEventThresholdMinutes int32
+	ReplicaKmsKeyID              string
+	OwnerOverride                string
+}
+
+type ReplicationRuleFilter struct {
+	Prefix string
+	Tags   []Tag
+	And    bool
 }
 
 type BucketReplicationInfoOutput struct {
+	Role             string
 	ReplicationRules []BucketReplicationRule
 }
 
@@ -85,23 +312,74 @@ type BucketLifeCycleInfoInput struct {
 }
 
 type BucketLifeCycleRule struct {
-	ID     string
-	Status string
+	ID                                 string
+	Status                             string
+	Filter                             LifeCycleRuleFilter
+	Transitions                        []LifeCycleTransition
+	ExpirationDays                     int32
+	ExpirationDate                     *time.Time
+	ExpiredObjectDeleteMarker          bool
+	NoncurrentVersionTransitions       []NoncurrentVersionTransition
+	NoncurrentVersionExpirationDays    int32
+	AbortIncompleteMultipartUploadDays int32
+}
+
+// LifeCycleRuleFilter flattens the filter union. And is set when the rule combines
+// prefix and tags with the And operator.
+type LifeCycleRuleFilter struct {
+	Prefix string
+	Tags   []Tag
+	And    bool
+}
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+type LifeCycleTransition struct {
+	Days         int32
+	Date         *time.Time
+	StorageClass string
+}
+
+type NoncurrentVersionTransition struct {
+	NoncurrentDays int32
+	StorageClass   string
 }
 
 type BucketLifeCycleInfoOutput struct {
 	LifeCycleRules []BucketLifeCycleRule
 }
 
-var awsConfig aws.Config
+var DefaultSizeDistributionBounds = []int64{
+	1 << 10,
+	128 << 10,
+	1 << 20,
+	100 << 20,
+	5 << 30,
+}
+
+var sseAlgorithms = map[string]string{
+	"AES256":       "SSE-S3",
+	"aws:kms":      "SSE-KMS",
+	"aws:kms:dsse": "DSSE-KMS",
+}
 
-func NewS3Client() *S3Client {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+var DefaultAgeDistributionBounds = []int{7, 30, 90, 180, 365}
+
+// NewS3Client creates a client configured by cc, returning an error when the
+// AWS configuration cannot be loaded.
+func NewS3Client(c context.Context, cc ClientConfig) (*S3Client, error) {
+	cfg, err := loadConfig(c, cc)
+	if err != nil {
+		return nil, err
+	}
+	optFn, err := s3Options(cc)
 	if err != nil {
-		log.Fatal("Error while creating S3 Client: ", err)
+		return nil, err
 	}
-	awsConfig = cfg
-	return &S3Client{Api: s3.NewFromConfig(cfg)}
+	return &S3Client{Api: s3.NewFromConfig(cfg, optFn), Regions: NewRegionCache(), Region: cfg.Region}, nil
 }
 
 func (s3c S3Client) GetAllBuckets(c context.Context, params *AllBucketsInput) (AllBucketsOutput, error) {
@@ -111,20 +389,30 @@ func (s3c S3Client) GetAllBuckets(c context.Context, params *AllBucketsInput) (A
 		return AllBucketsOutput{}, err
 	}
 
+	bf, err := newBucketFilter(params)
+	if err != nil {
+		return AllBucketsOutput{}, err
+	}
+
 	var bs AllBucketsOutput
 
 	for _, b := range lbo.Buckets {
-		if params.FilterBucketName != "" {
-			if !strings.Contains(*b.Name, params.FilterBucketName) {
-				continue
-			}
-		}
 		nb := Bucket{
 			Name:         *b.Name,
 			CreationDate: *b.CreationDate,
 		}
+		if !bf.match(nb) {
+			continue
+		}
 		bs.Buckets = append(bs.Buckets, nb)
 	}
+	bf.warnMissing()
+
+	s3c.resolveRegions(c, bs.Buckets)
+
+	if len(params.IncludeRegions) > 0 || len(params.ExcludeRegions) > 0 {
+		bs.Buckets = filterRegions(bs.Buckets, params.IncludeRegions, params.ExcludeRegions)
+	}
 
 	return bs, nil
 }
@@ -135,39 +423,460 @@ func (s3c S3Client) GetObjectStats(c context.Context,
 		return nil, errors.New("Bucket name is required")
 	}
 
-	bs := ObjectStatsOutput{}
+	in := *params
+	if in.ReferenceDate.IsZero() {
+		in.ReferenceDate = time.Now()
+	}
 
-	p := s3.ListObjectsV2Input{
-		Bucket: &params.BucketName,
-		Prefix: &params.Prefix,
+	m, err := newObjectMatcher(params.Filter)
+	if err != nil {
+		return nil, err
 	}
 
-	loc, err := s3c.Api.GetBucketLocation(c, &s3.GetBucketLocationInput{Bucket: &params.BucketName})
+	region, err := s3c.regionOption(c, params.BucketName)
 	if err != nil {
-		log.Error("Bucket location not found ", err)
 		return nil, err
 	}
-	if loc.LocationConstraint == "" {
-		loc.LocationConstraint = "us-east-1"
+
+	p := params.Progress
+	if p == nil {
+		p = NewProgress()
+	}
+	if params.Resume != nil {
+		in.ReferenceDate = params.Resume.ReferenceDate
+		p.restore(&in, params.Resume)
+	} else {
+		base := newObjectStatsOutput(&in)
+		var shards []objectShard
+		for _, prefix := range objectPrefixes(params.Prefix, params.Prefixes) {
+			switch params.ShardMode {
+			case ShardNone:
+				shards = append(shards, objectShard{prefix: prefix})
+			case ShardPrefix:
+				ps, err := s3c.prefixShards(c, &in, prefix, base, m, region)
+				if err != nil {
+					return nil, err
+				}
+				shards = append(shards, ps...)
+			case ShardRange:
+				boundaries := params.ShardBoundaries
+				if len(boundaries) == 0 {
+					boundaries = DefaultShardBoundaries
+				}
+				shards = append(shards, rangeShards(prefix, boundaries)...)
+			default:
+				return nil, fmt.Errorf("unknown shard mode %q", params.ShardMode)
+			}
+		}
+		p.start(&in, base, shards)
 	}
 
-	pg := s3.NewListObjectsV2Paginator(s3c.Api, &p)
+	if err := s3c.listShards(c, &in, p, m, region); err != nil {
+		return nil, err
+	}
 
-	for pg.HasMorePages() {
-		loo, err := pg.NextPage(c, func(o *s3.Options) { o.Region = string(loc.LocationConstraint) })
-		if err != nil {
-			log.Error("Error while listing objects: ", err)
-			return nil, err
+	return p.result(&in), nil
+}
+
+func newObjectStatsOutput(params *ObjectStatsInput) *ObjectStatsOutput {
+	bs := &ObjectStatsOutput{}
+
+	bounds := params.SizeDistributionBounds
+	if len(bounds) == 0 {
+		bounds = DefaultSizeDistributionBounds
+	}
+	bs.SizeDistribution = NewSizeDistribution(bounds)
+	bs.AgeDistribution = NewAgeDistribution(DefaultAgeDistributionBounds)
+	bs.referenceDate = params.ReferenceDate
+	if params.PrefixDepth > 0 {
+		basePrefix := ""
+		if ps := objectPrefixes(params.Prefix, params.Prefixes); len(ps) == 1 {
+			basePrefix = ps[0]
+		}
+		bs.prefixes = newPrefixAggregator(basePrefix, params.Delimiter, params.PrefixDepth)
+	}
+	if params.TopN > 0 {
+		bs.largestFiles = newTopObjects(params.TopN)
+	}
+
+	return bs
+}
+
+func (bs *ObjectStatsOutput) finalize() {
+	bs.SizeInKB = bs.SizeInBytes / 1024
+	if bs.TotalFiles > 0 {
+		bs.AverageFileSizeInBytes = bs.SizeInBytes / bs.TotalFiles
+	}
+
+	sort.Slice(bs.StorageClasses, func(i, j int) bool {
+		return bs.StorageClasses[i].StorageClass < bs.StorageClasses[j].StorageClass
+	})
+
+	if bs.prefixes != nil {
+		bs.Prefixes = bs.prefixes.tree()
+	}
+
+	if bs.largestFiles != nil {
+		bs.LargestFiles = bs.largestFiles.list()
+		if bs.prefixes != nil {
+			bs.LargestPrefixes = largestPrefixes(bs.prefixes.leaves(), bs.largestFiles.n)
+		}
+	}
+}
+
+func (bs *ObjectStatsOutput) addObject(o types.Object) {
+	bs.TotalFiles += 1
+	bs.SizeInBytes += o.Size
+
+	if bs.TotalFiles == 1 {
+		bs.MostRecentFile, bs.MostRecentFileModifiedDate = *o.Key, *o.LastModified
+		bs.OldestFile, bs.OldestFileModifiedDate = *o.Key, *o.LastModified
+		bs.LargestFile, bs.LargestFileSizeInBytes = *o.Key, o.Size
+		bs.SmallestFile, bs.SmallestFileSizeInBytes = *o.Key, o.Size
+	} else {
+		if r := comparedate.GetMostRecentDate(&bs.MostRecentFileModifiedDate, o.LastModified); r == o.LastModified {
+			bs.MostRecentFile, bs.MostRecentFileModifiedDate = *o.Key, *r
+		}
+		if r := comparedate.GetOldestDate(&bs.OldestFileModifiedDate, o.LastModified); r == o.LastModified {
+			bs.OldestFile, bs.OldestFileModifiedDate = *o.Key, *r
+		}
+		if r := comparesize.GetLargestSize(&bs.LargestFileSizeInBytes, &o.Size); r == &o.Size {
+			bs.LargestFile, bs.LargestFileSizeInBytes = *o.Key, *r
+		}
+		if r := comparesize.GetSmallestSize(&bs.SmallestFileSizeInBytes, &o.Size); r == &o.Size {
+			bs.SmallestFile, bs.SmallestFileSizeInBytes = *o.Key, *r
+		}
+	}
+
+	bs.addToStorageClass(o)
+	bs.addToSizeDistribution(o)
+	bs.addToAgeDistribution(o)
+
+	if bs.prefixes != nil {
+		bs.prefixes.add(o)
+	}
+
+	if bs.largestFiles != nil {
+		bs.largestFiles.add(o)
+	}
+}
+
+// merge adds the partial aggregate of another listing of the same bucket, built
+// with the same input, to bs.
+func (bs *ObjectStatsOutput) merge(o *ObjectStatsOutput) {
+	if o.TotalFiles == 0 {
+		return
+	}
+
+	if bs.TotalFiles == 0 {
+		bs.MostRecentFile, bs.MostRecentFileModifiedDate = o.MostRecentFile, o.MostRecentFileModifiedDate
+		bs.OldestFile, bs.OldestFileModifiedDate = o.OldestFile, o.OldestFileModifiedDate
+		bs.LargestFile, bs.LargestFileSizeInBytes = o.LargestFile, o.LargestFileSizeInBytes
+		bs.SmallestFile, bs.SmallestFileSizeInBytes = o.SmallestFile, o.SmallestFileSizeInBytes
+	} else {
+		if r := comparedate.GetMostRecentDate(&bs.MostRecentFileModifiedDate, &o.MostRecentFileModifiedDate); r == &o.MostRecentFileModifiedDate {
+			bs.MostRecentFile, bs.MostRecentFileModifiedDate = o.MostRecentFile, *r
+		}
+		if r := comparedate.GetOldestDate(&bs.OldestFileModifiedDate, &o.OldestFileModifiedDate); r == &o.OldestFileModifiedDate {
+			bs.OldestFile, bs.OldestFileModifiedDate = o.OldestFile, *r
+		}
+		if r := comparesize.GetLargestSize(&bs.LargestFileSizeInBytes, &o.LargestFileSizeInBytes); r == &o.LargestFileSizeInBytes {
+			bs.LargestFile, bs.LargestFileSizeInBytes = o.LargestFile, *r
+		}
+		if r := comparesize.GetSmallestSize(&bs.SmallestFileSizeInBytes, &o.SmallestFileSizeInBytes); r == &o.SmallestFileSizeInBytes {
+			bs.SmallestFile, bs.SmallestFileSizeInBytes = o.SmallestFile, *r
+		}
+	}
+	bs.TotalFiles += o.TotalFiles
+	bs.SizeInBytes += o.SizeInBytes
+
+	for _, sc := range o.StorageClasses {
+		bs.mergeStorageClass(sc)
+	}
+	for i := range o.SizeDistribution {
+		bs.SizeDistribution[i].TotalFiles += o.SizeDistribution[i].TotalFiles
+		bs.SizeDistribution[i].SizeInBytes += o.SizeDistribution[i].SizeInBytes
+	}
+	for i := range o.AgeDistribution {
+		bs.AgeDistribution[i].TotalFiles += o.AgeDistribution[i].TotalFiles
+		bs.AgeDistribution[i].SizeInBytes += o.AgeDistribution[i].SizeInBytes
+	}
+
+	if bs.prefixes != nil {
+		bs.prefixes.merge(o.prefixes)
+	}
+
+	if bs.largestFiles != nil {
+		bs.largestFiles.merge(o.largestFiles)
+	}
+}
+
+func (bs *ObjectStatsOutput) mergeStorageClass(sc StorageClass) {
+	for i := range bs.StorageClasses {
+		if bs.StorageClasses[i].StorageClass == sc.StorageClass {
+			bs.StorageClasses[i].TotalFiles += sc.TotalFiles
+			bs.StorageClasses[i].SizeInBytes += sc.SizeInBytes
+			return
+		}
+	}
+
+	bs.StorageClasses = append(bs.StorageClasses, sc)
+}
+
+// addToAgeDistribution accounts objects modified after the reference date, as
+// with clock skew, in the first range so every object is in the histogram.
+func (bs *ObjectStatsOutput) addToAgeDistribution(o types.Object) {
+	age := comparedate.GetAgeInDays(&bs.referenceDate, o.LastModified)
+	if age < 0 {
+		age = 0
+	}
+	for i := range bs.AgeDistribution {
+		ar := &bs.AgeDistribution[i]
+		if age >= ar.MinAgeInDays && (ar.MaxAgeInDays == 0 || age < ar.MaxAgeInDays) {
+			ar.TotalFiles += 1
+			ar.SizeInBytes += o.Size
+			return
+		}
+	}
+}
+
+func (bs *ObjectStatsOutput) addToSizeDistribution(o types.Object) {
+	for i := range bs.SizeDistribution {
+		sr := &bs.SizeDistribution[i]
+		if o.Size >= sr.MinSizeInBytes && (sr.MaxSizeInBytes == 0 || o.Size < sr.MaxSizeInBytes) {
+			sr.TotalFiles += 1
+			sr.SizeInBytes += o.Size
+			return
+		}
+	}
+}
+
+// NewSizeDistribution builds the histogram ranges for the given upper bounds.
+// The last range has no upper bound, which is represented by MaxSizeInBytes 0.
+func NewSizeDistribution(bounds []int64) []SizeRange {
+	b := append([]int64{}, bounds...)
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+
+	var sd []SizeRange
+	var min int64
+	for _, max := range b {
+		if max <= min {
+			continue
+		}
+		label := bytesize.Format(min) + " - " + bytesize.Format(max)
+		if min == 0 {
+			label = "< " + bytesize.Format(max)
+		}
+		sd = append(sd, SizeRange{Label: label, MinSizeInBytes: min, MaxSizeInBytes: max})
+		min = max
+	}
+
+	return append(sd, SizeRange{Label: ">= " + bytesize.Format(min), MinSizeInBytes: min})
+}
+
+// NewAgeDistribution builds the histogram ranges for the given upper bounds in days.
+// The last range has no upper bound, which is represented by MaxAgeInDays 0.
+func NewAgeDistribution(bounds []int) []AgeRange {
+	b := append([]int{}, bounds...)
+	sort.Ints(b)
+
+	var ad []AgeRange
+	var min int
+	for _, max := range b {
+		if max <= min {
+			continue
+		}
+		label := fmt.Sprintf("%v - %v days", min, max)
+		if min == 0 {
+			label = fmt.Sprintf("< %v days", max)
+		}
+		ad = append(ad, AgeRange{Label: label, MinAgeInDays: min, MaxAgeInDays: max})
+		min = max
+	}
+
+	return append(ad, AgeRange{Label: fmt.Sprintf(">= %v days", min), MinAgeInDays: min})
+}
+
+func (bs *ObjectStatsOutput) addToStorageClass(o types.Object) {
+	sc := string(o.StorageClass)
+	if sc == "" {
+		sc = string(types.ObjectStorageClassStandard)
+	}
+
+	for i := range bs.StorageClasses {
+		if bs.StorageClasses[i].StorageClass == sc {
+			bs.StorageClasses[i].TotalFiles += 1
+			bs.StorageClasses[i].SizeInBytes += o.Size
+			return
+		}
+	}
+
+	bs.StorageClasses = append(bs.StorageClasses, StorageClass{
+		StorageClass: sc,
+		TotalFiles:   1,
+		SizeInBytes:  o.Size,
+	})
+}
+
+func (s3c S3Client) GetObjectVersionStats(c context.Context,
+	params *ObjectVersionStatsInput) (ObjectVersionStatsOutput, error) {
+	if params.BucketName == "" {
+		return ObjectVersionStatsOutput{}, errors.New("Bucket name is required")
+	}
+
+	m, err := newObjectMatcher(params.Filter)
+	if err != nil {
+		return ObjectVersionStatsOutput{}, err
+	}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return ObjectVersionStatsOutput{}, err
+	}
+
+	vs := ObjectVersionStatsOutput{}
+
+	for _, prefix := range objectPrefixes(params.Prefix, params.Prefixes) {
+		p := &s3.ListObjectVersionsInput{
+			Bucket: &params.BucketName,
+			Prefix: aws.String(prefix),
+		}
+
+		for {
+			lov, err := s3c.Api.ListObjectVersions(c, p, region)
+			if err != nil {
+				log.Error("Error while listing object versions: ", err)
+				return ObjectVersionStatsOutput{}, err
+			}
+
+			for _, v := range lov.Versions {
+				if !m.matchVersion(v) {
+					continue
+				}
+				if v.IsLatest {
+					vs.CurrentFiles += 1
+					vs.CurrentSizeInBytes += v.Size
+					continue
+				}
+
+				vs.NoncurrentFiles += 1
+				vs.NoncurrentSizeInBytes += v.Size
+				if vs.NoncurrentFiles == 1 {
+					vs.OldestNoncurrentFile, vs.OldestNoncurrentFileModifiedDate = *v.Key, *v.LastModified
+				} else if r := comparedate.GetOldestDate(&vs.OldestNoncurrentFileModifiedDate, v.LastModified); r == v.LastModified {
+					vs.OldestNoncurrentFile, vs.OldestNoncurrentFileModifiedDate = *v.Key, *r
+				}
+			}
+
+			for _, d := range lov.DeleteMarkers {
+				if m.matchDeleteMarker(d) {
+					vs.DeleteMarkers += 1
+				}
+			}
+
+			if !lov.IsTruncated {
+				break
+			}
+			p.KeyMarker = lov.NextKeyMarker
+			p.VersionIdMarker = lov.NextVersionIdMarker
+		}
+	}
+
+	return vs, nil
+}
+
+func (s3c S3Client) GetMultipartUploadStats(c context.Context,
+	params *MultipartUploadStatsInput) (MultipartUploadStatsOutput, error) {
+	if params.BucketName == "" {
+		return MultipartUploadStatsOutput{}, errors.New("Bucket name is required")
+	}
+
+	m, err := newObjectMatcher(params.Filter)
+	if err != nil {
+		return MultipartUploadStatsOutput{}, err
+	}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return MultipartUploadStatsOutput{}, err
+	}
+
+	ms := MultipartUploadStatsOutput{}
+
+	for _, prefix := range objectPrefixes(params.Prefix, params.Prefixes) {
+		p := &s3.ListMultipartUploadsInput{
+			Bucket: &params.BucketName,
+			Prefix: aws.String(prefix),
+		}
+
+		for {
+			lmu, err := s3c.Api.ListMultipartUploads(c, p, region)
+			if err != nil {
+				log.Error("Error while listing multipart uploads: ", err)
+				return MultipartUploadStatsOutput{}, err
+			}
+
+			for _, u := range lmu.Uploads {
+				if !m.matchUpload(u) {
+					continue
+				}
+				mu := MultipartUpload{
+					Key:          *u.Key,
+					UploadID:     *u.UploadId,
+					Initiated:    *u.Initiated,
+					StorageClass: string(u.StorageClass),
+				}
+
+				pg := s3.NewListPartsPaginator(s3c.Api, &s3.ListPartsInput{
+					Bucket:   &params.BucketName,
+					Key:      u.Key,
+					UploadId: u.UploadId,
+				})
+				for pg.HasMorePages() {
+					lp, err := pg.NextPage(c, region)
+					if err != nil {
+						log.Error("Error while listing multipart upload parts: ", err)
+						return MultipartUploadStatsOutput{}, err
+					}
+					for _, pt := range lp.Parts {
+						mu.TotalParts += 1
+						mu.SizeInBytes += pt.Size
+					}
+				}
+
+				if !m.matchSize(mu.SizeInBytes) {
+					continue
+				}
+				ms.TotalUploads += 1
+				ms.SizeInBytes += mu.SizeInBytes
+				ms.Uploads = append(ms.Uploads, mu)
+			}
+
+			if !lmu.IsTruncated {
+				break
+			}
+			p.KeyMarker = lmu.NextKeyMarker
+			p.UploadIdMarker = lmu.NextUploadIdMarker
 		}
+	}
 
-		for _, o := range loo.Contents {
-			bs.TotalFiles += 1
-			bs.SizeInKB += (o.Size / 1024)
-			bs.MostRecentFileModifiedDate = *comparedate.GetMostRecentDate(&bs.MostRecentFileModifiedDate, o.LastModified)
+	lc, err := s3c.Api.GetBucketLifecycleConfiguration(c, &s3.GetBucketLifecycleConfigurationInput{Bucket: &params.BucketName}, region)
+	if err != nil {
+		var re *awshttp.ResponseError
+		if !errors.As(err, &re) || re.Response.StatusCode != 404 {
+			log.Errorf("Error while getting lifecycle info: %v", err)
+			return MultipartUploadStatsOutput{}, err
+		}
+		log.Debugf("Lifecycle rules not found for bucket %v", params.BucketName)
+	} else {
+		for _, r := range lc.Rules {
+			if r.Status == types.ExpirationStatusEnabled && r.AbortIncompleteMultipartUpload != nil {
+				ms.HasAbortIncompleteUploadsRule = true
+			}
 		}
 	}
 
-	return &bs, nil
+	return ms, nil
 }
 
 func (s3c S3Client) GetBucketReplicationInfo(c context.Context,
@@ -178,16 +887,12 @@ func (s3c S3Client) GetBucketReplicationInfo(c context.Context,
 
 	p := &s3.GetBucketReplicationInput{Bucket: &params.BucketName}
 
-	loc, err := s3c.Api.GetBucketLocation(c, &s3.GetBucketLocationInput{Bucket: &params.BucketName})
+	region, err := s3c.regionOption(c, params.BucketName)
 	if err != nil {
-		log.Error("Bucket location not found ", err)
 		return BucketReplicationInfoOutput{}, err
 	}
-	if loc.LocationConstraint == "" {
-		loc.LocationConstraint = "us-east-1"
-	}
 
-	rep, err := s3c.Api.GetBucketReplication(c, p, func(o *s3.Options) { o.Region = string(loc.LocationConstraint) })
+	rep, err := s3c.Api.GetBucketReplication(c, p, region)
 	if err != nil {
 		var re *awshttp.ResponseError
 		if errors.As(err, &re) {
@@ -201,7 +906,7 @@ func (s3c S3Client) GetBucketReplicationInfo(c context.Context,
 		return BucketReplicationInfoOutput{}, err
 	}
 
-	bri := BucketReplicationInfoOutput{}
+	bri := BucketReplicationInfoOutput{Role: aws.ToString(rep.ReplicationConfiguration.Role)}
 
 	for _, rr := range rep.ReplicationConfiguration.Rules {
 		var acc string
@@ -220,19 +925,255 @@ func (s3c S3Client) GetBucketReplicationInfo(c context.Context,
 			sc = string(rr.Destination.StorageClass)
 		}
 
-		bri.ReplicationRules = append(bri.ReplicationRules, BucketReplicationRule{
+		brr := BucketReplicationRule{
 			DestinationAccount: acc,
 			DestinationBucket:  *rr.Destination.Bucket,
 			ID:                 *rr.ID,
 			Priority:           rr.Priority,
 			Status:             string(rr.Status),
 			StorageClass:       sc,
-		})
+		}
+		setReplicationRuleDetails(&brr, rr)
+
+		bri.ReplicationRules = append(bri.ReplicationRules, brr)
 	}
 
 	return bri, err
 }
 
+func (s3c S3Client) GetBucketVersioningInfo(c context.Context,
+	params *BucketVersioningInfoInput) (BucketVersioningInfoOutput, error) {
+	if params.BucketName == "" {
+		return BucketVersioningInfoOutput{}, errors.New("Bucket name is required")
+	}
+
+	p := &s3.GetBucketVersioningInput{Bucket: &params.BucketName}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return BucketVersioningInfoOutput{}, err
+	}
+
+	v, err := s3c.Api.GetBucketVersioning(c, p, region)
+	if err != nil {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) && re.Response.StatusCode == 403 {
+			log.Debugf("User has no ownership of bucket %v", *p.Bucket)
+		}
+		log.Errorf("Error while getting versioning info: %v", err)
+		return BucketVersioningInfoOutput{}, err
+	}
+
+	vi := BucketVersioningInfoOutput{
+		Status:    string(v.Status),
+		MFADelete: string(v.MFADelete),
+	}
+	if vi.Status == "" {
+		vi.Status = "Never Enabled"
+	}
+	if vi.MFADelete == "" {
+		vi.MFADelete = string(types.MFADeleteStatusDisabled)
+	}
+
+	return vi, nil
+}
+
+func (s3c S3Client) GetBucketObjectLockInfo(c context.Context,
+	params *BucketObjectLockInfoInput) (BucketObjectLockInfoOutput, error) {
+	if params.BucketName == "" {
+		return BucketObjectLockInfoOutput{}, errors.New("Bucket name is required")
+	}
+
+	p := &s3.GetObjectLockConfigurationInput{Bucket: &params.BucketName}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return BucketObjectLockInfoOutput{}, err
+	}
+
+	ol, err := s3c.Api.GetObjectLockConfiguration(c, p, region)
+	if err != nil {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) {
+			if re.Response.StatusCode == 404 {
+				log.Debugf("Object lock configuration not found for bucket %v", *p.Bucket)
+				return BucketObjectLockInfoOutput{}, nil
+			} else if re.Response.StatusCode == 403 {
+				log.Debugf("User has no ownership of bucket %v", *p.Bucket)
+			}
+		}
+		log.Errorf("Error while getting object lock info: %v", err)
+		return BucketObjectLockInfoOutput{}, err
+	}
+
+	oli := BucketObjectLockInfoOutput{}
+	if ol.ObjectLockConfiguration == nil {
+		return oli, nil
+	}
+
+	oli.Enabled = ol.ObjectLockConfiguration.ObjectLockEnabled == types.ObjectLockEnabledEnabled
+	if r := ol.ObjectLockConfiguration.Rule; r != nil && r.DefaultRetention != nil {
+		oli.RetentionMode = string(r.DefaultRetention.Mode)
+		oli.RetentionDays = r.DefaultRetention.Days
+		oli.RetentionYears = r.DefaultRetention.Years
+	}
+
+	return oli, nil
+}
+
+func (s3c S3Client) GetBucketEncryptionInfo(c context.Context,
+	params *BucketEncryptionInfoInput) (BucketEncryptionInfoOutput, error) {
+	if params.BucketName == "" {
+		return BucketEncryptionInfoOutput{}, errors.New("Bucket name is required")
+	}
+
+	p := &s3.GetBucketEncryptionInput{Bucket: &params.BucketName}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return BucketEncryptionInfoOutput{}, err
+	}
+
+	enc, err := s3c.Api.GetBucketEncryption(c, p, region)
+	if err != nil {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) {
+			if re.Response.StatusCode == 404 {
+				log.Debugf("Encryption configuration not found for bucket %v", *p.Bucket)
+				return BucketEncryptionInfoOutput{Algorithm: "None"}, nil
+			} else if re.Response.StatusCode == 403 {
+				log.Debugf("User has no ownership of bucket %v", *p.Bucket)
+			}
+		}
+		log.Errorf("Error while getting encryption info: %v", err)
+		return BucketEncryptionInfoOutput{}, err
+	}
+
+	bei := BucketEncryptionInfoOutput{Algorithm: "None"}
+	if enc.ServerSideEncryptionConfiguration == nil {
+		return bei, nil
+	}
+
+	for _, r := range enc.ServerSideEncryptionConfiguration.Rules {
+		if r.ApplyServerSideEncryptionByDefault == nil {
+			continue
+		}
+		d := r.ApplyServerSideEncryptionByDefault
+		bei.Algorithm = string(d.SSEAlgorithm)
+		if a, ok := sseAlgorithms[bei.Algorithm]; ok {
+			bei.Algorithm = a
+		}
+		if d.KMSMasterKeyID != nil {
+			bei.KMSMasterKeyID = *d.KMSMasterKeyID
+		}
+		bei.BucketKeyEnabled = r.BucketKeyEnabled
+	}
+
+	return bei, nil
+}
+
+func (s3c S3Client) GetBucketPublicAccessInfo(c context.Context,
+	params *BucketPublicAccessInfoInput) (BucketPublicAccessInfoOutput, error) {
+	if params.BucketName == "" {
+		return BucketPublicAccessInfoOutput{}, errors.New("Bucket name is required")
+	}
+
+	region, err := s3c.regionOption(c, params.BucketName)
+	if err != nil {
+		return BucketPublicAccessInfoOutput{}, err
+	}
+
+	pai := BucketPublicAccessInfoOutput{}
+	unknown := false
+
+	// 404 means the configuration does not exist and 403 means it cannot be read,
+	// any other error aborts the collection for this bucket.
+	skip := func(err error, what string) (bool, error) {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) {
+			if re.Response.StatusCode == 404 {
+				log.Debugf("%v not found for bucket %v", what, params.BucketName)
+				return true, nil
+			} else if re.Response.StatusCode == 403 {
+				log.Debugf("User has no permission to read %v of bucket %v", what, params.BucketName)
+				unknown = true
+				return true, nil
+			}
+		}
+		log.Errorf("Error while getting %v: %v", what, err)
+		return false, err
+	}
+
+	pab, err := s3c.Api.GetPublicAccessBlock(c, &s3.GetPublicAccessBlockInput{Bucket: &params.BucketName}, region)
+	if err != nil {
+		if ok, err := skip(err, "public access block"); !ok {
+			return BucketPublicAccessInfoOutput{}, err
+		}
+	} else if cfg := pab.PublicAccessBlockConfiguration; cfg != nil {
+		pai.PublicAccessBlockConfigured = true
+		pai.BlockPublicAcls = cfg.BlockPublicAcls
+		pai.IgnorePublicAcls = cfg.IgnorePublicAcls
+		pai.BlockPublicPolicy = cfg.BlockPublicPolicy
+		pai.RestrictPublicBuckets = cfg.RestrictPublicBuckets
+	}
+
+	ps, err := s3c.Api.GetBucketPolicyStatus(c, &s3.GetBucketPolicyStatusInput{Bucket: &params.BucketName}, region)
+	if err != nil {
+		if ok, err := skip(err, "policy status"); !ok {
+			return BucketPublicAccessInfoOutput{}, err
+		}
+	} else if ps.PolicyStatus != nil {
+		pai.PolicyIsPublic = ps.PolicyStatus.IsPublic
+	}
+
+	bp, err := s3c.Api.GetBucketPolicy(c, &s3.GetBucketPolicyInput{Bucket: &params.BucketName}, region)
+	if err != nil {
+		if ok, err := skip(err, "bucket policy"); !ok {
+			return BucketPublicAccessInfoOutput{}, err
+		}
+	} else if bp.Policy != nil {
+		pai.Policy = *bp.Policy
+		pai.PublicPolicyStatements, err = policy.PublicStatements(pai.Policy)
+		if err != nil {
+			log.Errorf("Error while parsing bucket policy of bucket %v: %v", params.BucketName, err)
+			unknown = true
+		}
+	}
+
+	acl, err := s3c.Api.GetBucketAcl(c, &s3.GetBucketAclInput{Bucket: &params.BucketName}, region)
+	if err != nil {
+		if ok, err := skip(err, "bucket acl"); !ok {
+			return BucketPublicAccessInfoOutput{}, err
+		}
+	} else {
+		for _, g := range acl.Grants {
+			if g.Grantee == nil || g.Grantee.URI == nil {
+				continue
+			}
+			for _, pg := range publicGroups {
+				if *g.Grantee.URI == pg {
+					pai.PublicACLGrants = append(pai.PublicACLGrants, pg+" "+string(g.Permission))
+				}
+			}
+		}
+	}
+
+	switch {
+	case pai.BlockPublicAcls && pai.IgnorePublicAcls && pai.BlockPublicPolicy && pai.RestrictPublicBuckets:
+		pai.Exposure = ExposureBlocked
+	case (pai.PolicyIsPublic || len(pai.PublicPolicyStatements) > 0) && !pai.RestrictPublicBuckets:
+		pai.Exposure = ExposurePolicyPublic
+	case len(pai.PublicACLGrants) > 0 && !pai.IgnorePublicAcls:
+		pai.Exposure = ExposureACLPublic
+	case unknown:
+		pai.Exposure = ExposureUnknown
+	default:
+		pai.Exposure = ExposureNotPublic
+	}
+
+	return pai, nil
+}
+
 func (s3c S3Client) GetBucketLifecycleInfo(c context.Context,
 	params *BucketLifeCycleInfoInput) (BucketLifeCycleInfoOutput, error) {
 	if params.BucketName == "" {
@@ -241,16 +1182,12 @@ func (s3c S3Client) GetBucketLifecycleInfo(c context.Context,
 
 	p := &s3.GetBucketLifecycleConfigurationInput{Bucket: &params.BucketName}
 
-	loc, err := s3c.Api.GetBucketLocation(c, &s3.GetBucketLocationInput{Bucket: &params.BucketName})
+	region, err := s3c.regionOption(c, params.BucketName)
 	if err != nil {
-		log.Error("Bucket location not found ", err)
 		return BucketLifeCycleInfoOutput{}, err
 	}
-	if loc.LocationConstraint == "" {
-		loc.LocationConstraint = "us-east-1"
-	}
 
-	lc, err := s3c.Api.GetBucketLifecycleConfiguration(c, p, func(o *s3.Options) { o.Region = string(loc.LocationConstraint) })
+	lc, err := s3c.Api.GetBucketLifecycleConfiguration(c, p, region)
 	if err != nil {
 		var re *awshttp.ResponseError
 		if errors.As(err, &re) {
@@ -269,11 +1206,117 @@ func (s3c S3Client) GetBucketLifecycleInfo(c context.Context,
 	lfr := []BucketLifeCycleRule{}
 
 	for _, r := range lc.Rules {
-		lfr = append(lfr, BucketLifeCycleRule{
-			ID:     *r.ID,
-			Status: string(r.Status),
-		})
+		lfr = append(lfr, newBucketLifeCycleRule(r))
 	}
 
 	return BucketLifeCycleInfoOutput{LifeCycleRules: lfr}, nil
 }
+
+func newBucketLifeCycleRule(r types.LifecycleRule) BucketLifeCycleRule {
+	lr := BucketLifeCycleRule{
+		ID:     aws.ToString(r.ID),
+		Status: string(r.Status),
+		Filter: LifeCycleRuleFilter{Prefix: aws.ToString(r.Prefix)},
+	}
+
+	switch f := r.Filter.(type) {
+	case *types.LifecycleRuleFilterMemberPrefix:
+		lr.Filter.Prefix = f.Value
+	case *types.LifecycleRuleFilterMemberTag:
+		lr.Filter.Tags = []Tag{{Key: aws.ToString(f.Value.Key), Value: aws.ToString(f.Value.Value)}}
+	case *types.LifecycleRuleFilterMemberAnd:
+		lr.Filter.And = true
+		lr.Filter.Prefix = aws.ToString(f.Value.Prefix)
+		for _, t := range f.Value.Tags {
+			lr.Filter.Tags = append(lr.Filter.Tags, Tag{Key: aws.ToString(t.Key), Value: aws.ToString(t.Value)})
+		}
+	}
+
+	for _, t := range r.Transitions {
+		lr.Transitions = append(lr.Transitions, LifeCycleTransition{
+			Days:         t.Days,
+			Date:         t.Date,
+			StorageClass: string(t.StorageClass),
+		})
+	}
+
+	if e := r.Expiration; e != nil {
+		lr.ExpirationDays = e.Days
+		lr.ExpirationDate = e.Date
+		lr.ExpiredObjectDeleteMarker = e.ExpiredObjectDeleteMarker
+	}
+
+	for _, t := range r.NoncurrentVersionTransitions {
+		lr.NoncurrentVersionTransitions = append(lr.NoncurrentVersionTransitions, NoncurrentVersionTransition{
+			NoncurrentDays: t.NoncurrentDays,
+			StorageClass:   string(t.StorageClass),
+		})
+	}
+
+	if e := r.NoncurrentVersionExpiration; e != nil {
+		lr.NoncurrentVersionExpirationDays = e.NoncurrentDays
+	}
+
+	if a := r.AbortIncompleteMultipartUpload; a != nil {
+		lr.AbortIncompleteMultipartUploadDays = a.DaysAfterInitiation
+	}
+
+	return lr
+}
+
+func setReplicationRuleDetails(brr *BucketReplicationRule, rr types.ReplicationRule) {
+	brr.Filter.Prefix = aws.ToString(rr.Prefix)
+
+	switch f := rr.Filter.(type) {
+	case *types.ReplicationRuleFilterMemberPrefix:
+		brr.Filter.Prefix = f.Value
+	case *types.ReplicationRuleFilterMemberTag:
+		brr.Filter.Tags = []Tag{{Key: aws.ToString(f.Value.Key), Value: aws.ToString(f.Value.Value)}}
+	case *types.ReplicationRuleFilterMemberAnd:
+		brr.Filter.And = true
+		brr.Filter.Prefix = aws.ToString(f.Value.Prefix)
+		for _, t := range f.Value.Tags {
+			brr.Filter.Tags = append(brr.Filter.Tags, Tag{Key: aws.ToString(t.Key), Value: aws.ToString(t.Value)})
+		}
+	}
+
+	if rr.DeleteMarkerReplication != nil {
+		brr.DeleteMarkerReplication = string(rr.DeleteMarkerReplication.Status)
+	}
+
+	if rr.ExistingObjectReplication != nil {
+		brr.ExistingObjectReplication = string(rr.ExistingObjectReplication.Status)
+	}
+
+	if ssc := rr.SourceSelectionCriteria; ssc != nil {
+		if ssc.ReplicaModifications != nil {
+			brr.ReplicaModifications = string(ssc.ReplicaModifications.Status)
+		}
+		if ssc.SseKmsEncryptedObjects != nil {
+			brr.SseKmsEncryptedObjects = string(ssc.SseKmsEncryptedObjects.Status)
+		}
+	}
+
+	d := rr.Destination
+	if rt := d.ReplicationTime; rt != nil {
+		brr.ReplicationTimeControl = string(rt.Status)
+		if rt.Time != nil {
+			brr.ReplicationTimeMinutes = rt.Time.Minutes
+		}
+	}
+
+	if m := d.Metrics; m != nil {
+		brr.Metrics = string(m.Status)
+		if m.EventThreshold != nil {
+			brr.MetricsEventThresholdMinutes = m.EventThreshold.Minutes
+		}
+	}
+
+	if d.EncryptionConfiguration != nil {
+		brr.ReplicaKmsKeyID = aws.ToString(d.EncryptionConfiguration.ReplicaKmsKeyID)
+	}
+
+	if d.AccessControlTranslation != nil {
+		brr.OwnerOverride = string(d.AccessControlTranslation.Owner)
+	}
+}
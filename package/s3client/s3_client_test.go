@@ -2,14 +2,25 @@ package s3client_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/elribeiro/s3-stats-tool/package/s3client"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 )
 
 type S3AwsClientMock struct{}
@@ -31,6 +42,27 @@ func (s3c S3AwsClientMock) ListBuckets(ctx context.Context, params *s3.ListBucke
 
 func (s3c S3AwsClientMock) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input,
 	optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if *params.Bucket == "bucket3" {
+		d := time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)
+		objects := []types.Object{
+			{Key: aws.String("team-a/2020/item1"), Size: 100, LastModified: &d},
+			{Key: aws.String("team-a/2021/item2"), Size: 200, LastModified: &d},
+			{Key: aws.String("team-a/item3"), Size: 300, LastModified: &d},
+			{Key: aws.String("team-b/item4"), Size: 400, LastModified: &d},
+			{Key: aws.String("item5"), Size: 500, LastModified: &d},
+		}
+		return listObjectsV2(params, objects), nil
+	}
+	if *params.Bucket == "bucket4" {
+		d := time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)
+		objects := []types.Object{
+			{Key: aws.String("z/item1"), Size: 200, LastModified: &d},
+			{Key: aws.String("m/item2"), Size: 100, LastModified: &d},
+			{Key: aws.String("a/item3"), Size: 100, LastModified: &d},
+		}
+		return listObjectsV2(params, objects), nil
+	}
+
 	if *params.Bucket != "bucket1" {
 		err := errors.New("Bucket Not Found")
 		return nil, err
@@ -39,8 +71,8 @@ func (s3c S3AwsClientMock) ListObjectsV2(ctx context.Context, params *s3.ListObj
 	d1 := time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)
 	d2 := time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC)
 	objects := []types.Object{
-		{Key: aws.String("item1"), Size: 3131, LastModified: &d1},
-		{Key: aws.String("item2"), Size: 3232, LastModified: &d2},
+		{Key: aws.String("item1"), Size: 3131, LastModified: &d1, StorageClass: types.ObjectStorageClassStandard},
+		{Key: aws.String("item2"), Size: 3232, LastModified: &d2, StorageClass: types.ObjectStorageClassGlacier},
 	}
 
 	output := &s3.ListObjectsV2Output{Contents: objects}
@@ -49,6 +81,33 @@ func (s3c S3AwsClientMock) ListObjectsV2(ctx context.Context, params *s3.ListObj
 
 }
 
+// listObjectsV2 sorts objects by key and applies the Prefix, StartAfter and
+// Delimiter of params, as S3 does.
+func listObjectsV2(params *s3.ListObjectsV2Input, objects []types.Object) *s3.ListObjectsV2Output {
+	objects = append([]types.Object{}, objects...)
+	sort.Slice(objects, func(i, j int) bool { return *objects[i].Key < *objects[j].Key })
+
+	output := &s3.ListObjectsV2Output{}
+	seen := map[string]bool{}
+	for _, o := range objects {
+		key := *o.Key
+		if !strings.HasPrefix(key, aws.ToString(params.Prefix)) || key <= aws.ToString(params.StartAfter) {
+			continue
+		}
+		d := aws.ToString(params.Delimiter)
+		if i := strings.Index(strings.TrimPrefix(key, aws.ToString(params.Prefix)), d); d != "" && i >= 0 {
+			cp := key[:len(aws.ToString(params.Prefix))+i+len(d)]
+			if !seen[cp] {
+				seen[cp] = true
+				output.CommonPrefixes = append(output.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(cp)})
+			}
+			continue
+		}
+		output.Contents = append(output.Contents, o)
+	}
+	return output
+}
+
 func (s3c S3AwsClientMock) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput,
 	optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
 	if *params.Bucket != "bucket1" {
@@ -56,14 +115,18 @@ func (s3c S3AwsClientMock) GetBucketReplication(ctx context.Context, params *s3.
 		return nil, err
 	}
 
-	d := &types.Destination{Bucket: aws.String("bucket1"), Account: aws.String("123456789123"), StorageClass: types.StorageClassDeepArchive}
+	d := &types.Destination{Bucket: aws.String("bucket1"), Account: aws.String("123456789123"), StorageClass: types.StorageClassDeepArchive,
+		ReplicationTime: &types.ReplicationTime{Status: types.ReplicationTimeStatusEnabled, Time: &types.ReplicationTimeValue{Minutes: 15}},
+		Metrics:         &types.Metrics{Status: types.MetricsStatusEnabled, EventThreshold: &types.ReplicationTimeValue{Minutes: 15}}}
 
 	rr := []types.ReplicationRule{
-		{Destination: d, Status: types.ReplicationRuleStatusEnabled, ID: aws.String("id1"), Priority: 1},
+		{Destination: d, Status: types.ReplicationRuleStatusEnabled, ID: aws.String("id1"), Priority: 1,
+			Filter:                  &types.ReplicationRuleFilterMemberPrefix{Value: "dr/"},
+			DeleteMarkerReplication: &types.DeleteMarkerReplication{Status: types.DeleteMarkerReplicationStatusEnabled}},
 		{Destination: d, Status: types.ReplicationRuleStatusDisabled, ID: aws.String("id2"), Priority: 2},
 	}
 
-	rc := types.ReplicationConfiguration{Rules: rr}
+	rc := types.ReplicationConfiguration{Rules: rr, Role: aws.String("arn:aws:iam::123456789123:role/replication")}
 
 	r := s3.GetBucketReplicationOutput{ReplicationConfiguration: &rc}
 
@@ -79,7 +142,18 @@ func (s3c S3AwsClientMock) GetBucketLifecycleConfiguration(ctx context.Context,
 	}
 
 	r := []types.LifecycleRule{
-		{Status: types.ExpirationStatusEnabled, ID: aws.String("id1")},
+		{Status: types.ExpirationStatusEnabled, ID: aws.String("id1"),
+			AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: 7},
+			Filter: &types.LifecycleRuleFilterMemberAnd{Value: types.LifecycleRuleAndOperator{
+				Prefix: aws.String("logs/"),
+				Tags:   []types.Tag{{Key: aws.String("team"), Value: aws.String("data")}},
+			}},
+			Transitions: []types.Transition{
+				{Days: 30, StorageClass: types.TransitionStorageClassStandardIa},
+				{Days: 90, StorageClass: types.TransitionStorageClassGlacier},
+			},
+			Expiration:                  &types.LifecycleExpiration{Days: 365},
+			NoncurrentVersionExpiration: &types.NoncurrentVersionExpiration{NoncurrentDays: 30}},
 		{Status: types.ExpirationStatusDisabled, ID: aws.String("id2")},
 	}
 
@@ -93,17 +167,157 @@ func (s3c S3AwsClientMock) GetBucketLocation(ctx context.Context, params *s3.Get
 	return &s3.GetBucketLocationOutput{LocationConstraint: types.BucketLocationConstraintApEast1}, nil
 }
 
+func (s3c S3AwsClientMock) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput,
+	optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	if *params.Bucket != "bucket1" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	d1 := time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)
+	d2 := time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC)
+
+	if params.KeyMarker == nil {
+		versions := []types.ObjectVersion{
+			{Key: aws.String("item1"), Size: 3131, LastModified: &d2, IsLatest: true},
+			{Key: aws.String("item1"), Size: 1000, LastModified: &d1},
+		}
+		return &s3.ListObjectVersionsOutput{
+			Versions:            versions,
+			IsTruncated:         true,
+			NextKeyMarker:       aws.String("item1"),
+			NextVersionIdMarker: aws.String("v1"),
+		}, nil
+	}
+
+	versions := []types.ObjectVersion{
+		{Key: aws.String("item2"), Size: 2000, LastModified: &d2},
+	}
+	markers := []types.DeleteMarkerEntry{
+		{Key: aws.String("item2"), LastModified: &d2, IsLatest: true},
+	}
+
+	return &s3.ListObjectVersionsOutput{Versions: versions, DeleteMarkers: markers}, nil
+}
+
+func (s3c S3AwsClientMock) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput,
+	optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	if *params.Bucket != "bucket1" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	d := time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC)
+	uploads := []types.MultipartUpload{
+		{Key: aws.String("item1"), UploadId: aws.String("upload1"), Initiated: &d, StorageClass: types.StorageClassStandard},
+		{Key: aws.String("item2"), UploadId: aws.String("upload2"), Initiated: &d, StorageClass: types.StorageClassStandard},
+	}
+
+	return &s3.ListMultipartUploadsOutput{Uploads: uploads}, nil
+}
+
+func (s3c S3AwsClientMock) ListParts(ctx context.Context, params *s3.ListPartsInput,
+	optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	parts := []types.Part{
+		{PartNumber: 1, Size: 5 << 20},
+		{PartNumber: 2, Size: 1 << 20},
+	}
+
+	return &s3.ListPartsOutput{Parts: parts}, nil
+}
+
+func (s3c S3AwsClientMock) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	if *params.Bucket != "bucket1" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	return &s3.GetBucketVersioningOutput{Status: types.BucketVersioningStatusEnabled}, nil
+}
+
+func (s3c S3AwsClientMock) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput,
+	optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	if *params.Bucket != "bucket1" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	r := &types.ObjectLockRule{DefaultRetention: &types.DefaultRetention{Mode: types.ObjectLockRetentionModeCompliance, Days: 30}}
+	olc := &types.ObjectLockConfiguration{ObjectLockEnabled: types.ObjectLockEnabledEnabled, Rule: r}
+
+	return &s3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: olc}, nil
+}
+
+func (s3c S3AwsClientMock) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	if *params.Bucket != "bucket1" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	d := &types.ServerSideEncryptionByDefault{
+		SSEAlgorithm:   types.ServerSideEncryptionAwsKms,
+		KMSMasterKeyID: aws.String("arn:aws:kms:us-east-1:123456789123:key/key1"),
+	}
+	r := []types.ServerSideEncryptionRule{{ApplyServerSideEncryptionByDefault: d, BucketKeyEnabled: true}}
+	sse := &types.ServerSideEncryptionConfiguration{Rules: r}
+
+	return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: sse}, nil
+}
+
+func (s3c S3AwsClientMock) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput,
+	optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	if *params.Bucket == "bucket2" {
+		err := errors.New("Bucket Not Found")
+		return nil, err
+	}
+
+	blocked := *params.Bucket == "bucket1"
+	pab := &types.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       blocked,
+		IgnorePublicAcls:      blocked,
+		BlockPublicPolicy:     blocked,
+		RestrictPublicBuckets: blocked,
+	}
+
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: pab}, nil
+}
+
+func (s3c S3AwsClientMock) GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error) {
+	return &s3.GetBucketPolicyStatusOutput{PolicyStatus: &types.PolicyStatus{IsPublic: true}}, nil
+}
+
+func (s3c S3AwsClientMock) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	p := `{"Statement":[{"Sid":"public","Effect":"Allow","Principal":"*","Action":"s3:GetObject"}]}`
+
+	return &s3.GetBucketPolicyOutput{Policy: aws.String(p)}, nil
+}
+
+func (s3c S3AwsClientMock) GetBucketAcl(ctx context.Context, params *s3.GetBucketAclInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketAclOutput, error) {
+	g := []types.Grant{
+		{Grantee: &types.Grantee{URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers")}, Permission: types.PermissionRead},
+	}
+
+	return &s3.GetBucketAclOutput{Grants: g}, nil
+}
+
 func TestGetAllBuckets(t *testing.T) {
 	result := &struct {
 		wantTotalListSize    int
 		wantFilteredListSize int
 		wantName             string
 		wantCreationDate     time.Time
+		wantRegion           string
 	}{
 		wantTotalListSize:    2,
 		wantFilteredListSize: 1,
 		wantName:             "bucket1",
 		wantCreationDate:     time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC),
+		wantRegion:           "ap-east-1",
 	}
 
 	thisTime := time.Now()
@@ -122,23 +336,194 @@ func TestGetAllBuckets(t *testing.T) {
 		if b.CreationDate != result.wantCreationDate {
 			t.Errorf("Expecting %v, but got: %v", result.wantCreationDate, b.CreationDate)
 		}
+		if b.Region != result.wantRegion {
+			t.Errorf("Expecting %v, but got: %v", result.wantRegion, b.Region)
+		}
 	}
 
 	bsl, _ = s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{FilterBucketName: "bucket1"})
 	if len(bsl.Buckets) != result.wantFilteredListSize {
 		t.Errorf("Expecting %v, got %v ", result.wantFilteredListSize, len(bsl.Buckets))
 	}
+
+	for _, in := range []s3client.AllBucketsInput{
+		{IncludeBuckets: []string{"bucket*"}, ExcludeBuckets: []string{"*2"}},
+		{IncludeBuckets: []string{"regex:^bucket[0-1]$"}},
+		{BucketNames: []string{"bucket1", "bucket9"}},
+		{CreatedAfter: result.wantCreationDate, CreatedBefore: result.wantCreationDate.Add(time.Nanosecond),
+			ExcludeBuckets: []string{"regex:2$"}},
+	} {
+		bsl, _ = s3c.GetAllBuckets(context.TODO(), &in)
+		if len(bsl.Buckets) != 1 || bsl.Buckets[0].Name != result.wantName {
+			t.Errorf("Expecting only %v for %+v, got %+v", result.wantName, in, bsl.Buckets)
+		}
+	}
+
+	bsl, _ = s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{CreatedBefore: result.wantCreationDate})
+	if len(bsl.Buckets) != 0 {
+		t.Errorf("Expecting %v, got %v", 0, len(bsl.Buckets))
+	}
+
+	_, err := s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{IncludeBuckets: []string{"regex:("}})
+	if err == nil {
+		t.Errorf("Expecting error for invalid pattern")
+	}
+
+	for _, in := range []struct {
+		include, exclude []string
+		want             int
+	}{
+		{include: []string{"ap-*"}, want: 2},
+		{include: []string{"eu-*", "us-east-1"}, want: 0},
+		{exclude: []string{"eu-*"}, want: 2},
+		{include: []string{"ap-*"}, exclude: []string{"ap-east-1"}, want: 0},
+	} {
+		bsl, _ = s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{IncludeRegions: in.include, ExcludeRegions: in.exclude})
+		if len(bsl.Buckets) != in.want {
+			t.Errorf("Expecting %v for %v and %v, got %v", in.want, in.include, in.exclude, len(bsl.Buckets))
+		}
+	}
+}
+
+// S3AwsClientLocationMock counts the location lookups and returns the legacy EU
+// constraint.
+type S3AwsClientLocationMock struct {
+	S3AwsClientMock
+	lock  *sync.Mutex
+	calls *int
+}
+
+func (s3c S3AwsClientLocationMock) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	s3c.lock.Lock()
+	*s3c.calls += 1
+	s3c.lock.Unlock()
+	return &s3.GetBucketLocationOutput{LocationConstraint: types.BucketLocationConstraintEu}, nil
+}
+
+func TestBucketRegion(t *testing.T) {
+	calls := 0
+	api := S3AwsClientLocationMock{lock: &sync.Mutex{}, calls: &calls}
+	s3c := s3client.S3Client{Api: api, Regions: s3client.NewRegionCache()}
+
+	bsl, _ := s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{})
+	if bsl.Buckets[0].Region != "eu-west-1" {
+		t.Errorf("Expecting %v, got %v", "eu-west-1", bsl.Buckets[0].Region)
+	}
+
+	s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket1"})
+	s3c.GetBucketLifecycleInfo(context.TODO(), &s3client.BucketLifeCycleInfoInput{BucketName: "bucket1"})
+	s3c.GetBucketReplicationInfo(context.TODO(), &s3client.BucketReplicationInfoInput{BucketName: "bucket1"})
+	if calls != 2 {
+		t.Errorf("Expecting one location lookup per bucket, got %v for 2 buckets", calls)
+	}
+
+	for lc, want := range map[types.BucketLocationConstraint]string{
+		"":                                       "us-east-1",
+		types.BucketLocationConstraintEu:         "eu-west-1",
+		types.BucketLocationConstraintSaEast1:    "sa-east-1",
+		types.BucketLocationConstraintEuCentral1: "eu-central-1",
+	} {
+		if r := s3client.NormalizeRegion(lc); r != want {
+			t.Errorf("Expecting %v, got %v", want, r)
+		}
+	}
+}
+
+// S3AwsClientNoLocationMock is a service without GetBucketLocation, which
+// denies the lookups of bucket2.
+type S3AwsClientNoLocationMock struct {
+	S3AwsClientMock
+}
+
+func (s3c S3AwsClientNoLocationMock) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput,
+	optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	if *params.Bucket == "bucket2" {
+		return nil, &smithy.GenericAPIError{Code: "AccessDenied"}
+	}
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+}
+
+func TestBucketRegionNotImplemented(t *testing.T) {
+	for _, r := range []*struct {
+		region string
+		want   string
+	}{
+		{"", "us-east-1"},
+		{"eu-central-1", "eu-central-1"},
+	} {
+		s3c := s3client.S3Client{Api: S3AwsClientNoLocationMock{}, Regions: s3client.NewRegionCache(), Region: r.region}
+
+		region, err := s3c.BucketRegion(context.TODO(), "bucket1")
+		if err != nil || region != r.want {
+			t.Errorf("Expecting %v, got %v and %v", r.want, region, err)
+		}
+		if _, err := s3c.BucketRegion(context.TODO(), "bucket2"); err == nil {
+			t.Errorf("Expecting access denied error")
+		}
+	}
+}
+
+func TestGetAllBucketsUnresolvedRegion(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	s3c := s3client.S3Client{Api: S3AwsClientNoLocationMock{}, Regions: s3client.NewRegionCache()}
+
+	bsl, _ := s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{IncludeRegions: []string{"us-*"}})
+	if len(bsl.Buckets) != 1 || bsl.Buckets[0].Name != "bucket1" {
+		t.Errorf("Expecting only bucket1, got %v", bsl.Buckets)
+	}
+
+	warned := false
+	for _, e := range hook.AllEntries() {
+		if e.Level == log.WarnLevel && strings.HasPrefix(e.Message, "Skipping bucket bucket2") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expecting a warning for bucket2 skipped with an unresolved region")
+	}
+
+	bsl, _ = s3c.GetAllBuckets(context.TODO(), &s3client.AllBucketsInput{ExcludeRegions: []string{"us-*"}})
+	if len(bsl.Buckets) != 1 || bsl.Buckets[0].Name != "bucket2" {
+		t.Errorf("Expecting only bucket2, got %v", bsl.Buckets)
+	}
 }
 
 func TestGetObjectStats(t *testing.T) {
 	result := &struct {
 		wantSize         int64
+		wantSizeInBytes  int64
 		wantCreationDate time.Time
 		wantTotalFiles   int64
+		wantSCListSize   int
+		wantSCName       string
+		wantSCSize       int64
+		wantSCTotalFiles int64
+		wantRecentFile   string
+		wantOldestFile   string
+		wantLargestFile  string
+		wantSmallestFile string
+		wantAverageSize  int64
+		wantSDListSize   int
+		wantADListSize   int
 	}{
 		wantSize:         6363 / 1024,
+		wantSizeInBytes:  6363,
 		wantCreationDate: time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC),
 		wantTotalFiles:   2,
+		wantSCListSize:   2,
+		wantSCName:       "GLACIER",
+		wantSCSize:       3232,
+		wantSCTotalFiles: 1,
+		wantRecentFile:   "item2",
+		wantOldestFile:   "item1",
+		wantLargestFile:  "item2",
+		wantSmallestFile: "item1",
+		wantAverageSize:  6363 / 2,
+		wantSDListSize:   6,
+		wantADListSize:   6,
 	}
 
 	thisTime := time.Now()
@@ -146,16 +531,61 @@ func TestGetObjectStats(t *testing.T) {
 	api := S3AwsClientMock{}
 	s3c := s3client.S3Client{Api: api}
 
-	object, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket1"})
+	object, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{
+		BucketName:    "bucket1",
+		ReferenceDate: time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC),
+	})
 	if object.SizeInKB != result.wantSize {
 		t.Errorf("Expecting %v , got %v ", result.wantSize, object.SizeInKB)
 	}
+	if object.SizeInBytes != result.wantSizeInBytes {
+		t.Errorf("Expecting %v , got %v ", result.wantSizeInBytes, object.SizeInBytes)
+	}
 	if object.MostRecentFileModifiedDate != result.wantCreationDate {
 		t.Errorf("Expecting %v, got %v", result.wantCreationDate, object.MostRecentFileModifiedDate)
 	}
 	if object.TotalFiles != result.wantTotalFiles {
 		t.Errorf("Expecting %v, got %v", result.wantTotalFiles, object.TotalFiles)
 	}
+	if object.MostRecentFile != result.wantRecentFile {
+		t.Errorf("Expecting %v, got %v", result.wantRecentFile, object.MostRecentFile)
+	}
+	if object.OldestFile != result.wantOldestFile {
+		t.Errorf("Expecting %v, got %v", result.wantOldestFile, object.OldestFile)
+	}
+	if object.LargestFile != result.wantLargestFile {
+		t.Errorf("Expecting %v, got %v", result.wantLargestFile, object.LargestFile)
+	}
+	if object.SmallestFile != result.wantSmallestFile {
+		t.Errorf("Expecting %v, got %v", result.wantSmallestFile, object.SmallestFile)
+	}
+	if object.AverageFileSizeInBytes != result.wantAverageSize {
+		t.Errorf("Expecting %v, got %v", result.wantAverageSize, object.AverageFileSizeInBytes)
+	}
+	if len(object.SizeDistribution) != result.wantSDListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantSDListSize, len(object.SizeDistribution))
+	}
+	if object.SizeDistribution[1].TotalFiles != result.wantTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantTotalFiles, object.SizeDistribution[1].TotalFiles)
+	}
+	if len(object.AgeDistribution) != result.wantADListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantADListSize, len(object.AgeDistribution))
+	}
+	if object.AgeDistribution[1].TotalFiles != result.wantTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantTotalFiles, object.AgeDistribution[1].TotalFiles)
+	}
+	if len(object.StorageClasses) != result.wantSCListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantSCListSize, len(object.StorageClasses))
+	}
+	if object.StorageClasses[0].StorageClass != result.wantSCName {
+		t.Errorf("Expecting %v, got %v", result.wantSCName, object.StorageClasses[0].StorageClass)
+	}
+	if object.StorageClasses[0].SizeInBytes != result.wantSCSize {
+		t.Errorf("Expecting %v, got %v", result.wantSCSize, object.StorageClasses[0].SizeInBytes)
+	}
+	if object.StorageClasses[0].TotalFiles != result.wantSCTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantSCTotalFiles, object.StorageClasses[0].TotalFiles)
+	}
 
 	_, err := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket2"})
 	notFoundMsg := "Bucket Not Found"
@@ -169,6 +599,499 @@ func TestGetObjectStats(t *testing.T) {
 		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
 	}
 }
+func TestGetObjectStatsPrefixes(t *testing.T) {
+	result := &struct {
+		wantListSize      int
+		wantPrefix        string
+		wantSize          int64
+		wantTotalFiles    int64
+		wantSubListSize   int
+		wantSubPrefix     string
+		wantSubTotalFiles int64
+	}{
+		wantListSize:      2,
+		wantPrefix:        "team-a/",
+		wantSize:          600,
+		wantTotalFiles:    3,
+		wantSubListSize:   2,
+		wantSubPrefix:     "team-a/2020/",
+		wantSubTotalFiles: 1,
+	}
+
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	object, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3", PrefixDepth: 2})
+	if len(object.Prefixes) != result.wantListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantListSize, len(object.Prefixes))
+	}
+	p := object.Prefixes[0]
+	if p.Prefix != result.wantPrefix {
+		t.Errorf("Expecting %v, got %v", result.wantPrefix, p.Prefix)
+	}
+	if p.SizeInBytes != result.wantSize {
+		t.Errorf("Expecting %v, got %v", result.wantSize, p.SizeInBytes)
+	}
+	if p.TotalFiles != result.wantTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantTotalFiles, p.TotalFiles)
+	}
+	if len(p.Prefixes) != result.wantSubListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantSubListSize, len(p.Prefixes))
+	}
+	if p.Prefixes[0].Prefix != result.wantSubPrefix {
+		t.Errorf("Expecting %v, got %v", result.wantSubPrefix, p.Prefixes[0].Prefix)
+	}
+	if p.Prefixes[0].TotalFiles != result.wantSubTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantSubTotalFiles, p.Prefixes[0].TotalFiles)
+	}
+
+	object, _ = s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3"})
+	if len(object.Prefixes) != 0 {
+		t.Errorf("Expecting %v, got %v", 0, len(object.Prefixes))
+	}
+}
+
+func TestGetObjectStatsLargest(t *testing.T) {
+	result := &struct {
+		wantListSize       int
+		wantKey            string
+		wantSize           int64
+		wantPrefixListSize int
+		wantPrefixes       []string
+	}{
+		wantListSize:       2,
+		wantKey:            "item5",
+		wantSize:           500,
+		wantPrefixListSize: 2,
+		wantPrefixes:       []string{"team-b/", "team-a/2021/"},
+	}
+
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	object, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3", PrefixDepth: 2, TopN: 2})
+	if len(object.LargestFiles) != result.wantListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantListSize, len(object.LargestFiles))
+	}
+	if object.LargestFiles[0].Key != result.wantKey {
+		t.Errorf("Expecting %v, got %v", result.wantKey, object.LargestFiles[0].Key)
+	}
+	if object.LargestFiles[0].SizeInBytes != result.wantSize {
+		t.Errorf("Expecting %v, got %v", result.wantSize, object.LargestFiles[0].SizeInBytes)
+	}
+	if object.LargestFiles[0].StorageClass != string(types.ObjectStorageClassStandard) {
+		t.Errorf("Expecting %v, got %v", types.ObjectStorageClassStandard, object.LargestFiles[0].StorageClass)
+	}
+	if len(object.LargestPrefixes) != result.wantPrefixListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantPrefixListSize, len(object.LargestPrefixes))
+	}
+	for i, p := range result.wantPrefixes {
+		if object.LargestPrefixes[i].Prefix != p {
+			t.Errorf("Expecting %v, got %v", p, object.LargestPrefixes[i].Prefix)
+		}
+	}
+
+	object, _ = s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3"})
+	if len(object.LargestFiles) != 0 {
+		t.Errorf("Expecting %v, got %v", 0, len(object.LargestFiles))
+	}
+}
+
+func TestGetObjectStatsLargestTies(t *testing.T) {
+	result := &struct {
+		wantKeys     []string
+		wantPrefixes []string
+	}{
+		wantKeys:     []string{"z/item1", "a/item3"},
+		wantPrefixes: []string{"z/", "a/"},
+	}
+
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	for _, in := range []s3client.ObjectStatsInput{
+		{},
+		{ShardMode: s3client.ShardPrefix, Limiter: s3client.NewSemaphore(3)},
+	} {
+		in.BucketName, in.PrefixDepth, in.TopN = "bucket4", 1, 2
+		object, err := s3c.GetObjectStats(context.TODO(), &in)
+		if err != nil {
+			t.Fatalf("Expecting no error, got %v", err)
+		}
+		for i, k := range result.wantKeys {
+			if object.LargestFiles[i].Key != k {
+				t.Errorf("Expecting %v, got %v", k, object.LargestFiles[i].Key)
+			}
+		}
+		for i, p := range result.wantPrefixes {
+			if object.LargestPrefixes[i].Prefix != p {
+				t.Errorf("Expecting %v, got %v", p, object.LargestPrefixes[i].Prefix)
+			}
+		}
+	}
+}
+
+func TestGetObjectStatsSharded(t *testing.T) {
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	want, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3", PrefixDepth: 2, TopN: 2})
+
+	for _, in := range []s3client.ObjectStatsInput{
+		{ShardMode: s3client.ShardPrefix},
+		{ShardMode: s3client.ShardPrefix, Limiter: s3client.NewSemaphore(2)},
+		{ShardMode: s3client.ShardRange, ShardBoundaries: []string{"item5", "team-a/2021", "team-b"}},
+		{ShardMode: s3client.ShardRange, Limiter: s3client.NewSemaphore(2)},
+	} {
+		in.BucketName, in.PrefixDepth, in.TopN = "bucket3", 2, 2
+		object, err := s3c.GetObjectStats(context.TODO(), &in)
+		if err != nil {
+			t.Fatalf("Expecting no error for %v shards, got %v", in.ShardMode, err)
+		}
+		if object.TotalFiles != want.TotalFiles || object.SizeInBytes != want.SizeInBytes {
+			t.Errorf("Expecting %v files of %v bytes, got %v files of %v bytes",
+				want.TotalFiles, want.SizeInBytes, object.TotalFiles, object.SizeInBytes)
+		}
+		if object.LargestFile != want.LargestFile || object.SmallestFile != want.SmallestFile {
+			t.Errorf("Expecting %v and %v, got %v and %v", want.LargestFile, want.SmallestFile, object.LargestFile, object.SmallestFile)
+		}
+		if len(object.Prefixes) != len(want.Prefixes) || object.Prefixes[0].TotalFiles != want.Prefixes[0].TotalFiles {
+			t.Errorf("Expecting %v, got %v", want.Prefixes, object.Prefixes)
+		}
+		if object.LargestFiles[0].Key != want.LargestFiles[0].Key || object.LargestFiles[1].Key != want.LargestFiles[1].Key {
+			t.Errorf("Expecting %v, got %v", want.LargestFiles, object.LargestFiles)
+		}
+		if object.SizeDistribution[1].TotalFiles != want.SizeDistribution[1].TotalFiles {
+			t.Errorf("Expecting %v, got %v", want.SizeDistribution[1].TotalFiles, object.SizeDistribution[1].TotalFiles)
+		}
+	}
+
+	_, err := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3", ShardMode: "unknown"})
+	if err == nil {
+		t.Errorf("Expecting error for unknown shard mode")
+	}
+}
+
+// S3AwsClientPagedMock lists bucket3 two objects per page and fails the page
+// numbered failPage, counting pages in calls.
+type S3AwsClientPagedMock struct {
+	S3AwsClientMock
+	calls    *int
+	failPage int
+}
+
+func (s3c S3AwsClientPagedMock) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input,
+	optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	*s3c.calls += 1
+	if *s3c.calls == s3c.failPage {
+		return nil, errors.New("ExpiredToken")
+	}
+
+	output, _ := s3c.S3AwsClientMock.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:     params.Bucket,
+		Prefix:     params.Prefix,
+		StartAfter: params.StartAfter,
+	})
+	start, _ := strconv.Atoi(aws.ToString(params.ContinuationToken))
+	if len(output.Contents) > start+2 {
+		output.Contents = output.Contents[start : start+2]
+		output.IsTruncated = true
+		output.NextContinuationToken = aws.String(strconv.Itoa(start + 2))
+	} else {
+		output.Contents = output.Contents[start:]
+	}
+	return output, nil
+}
+
+func TestGetObjectStatsResume(t *testing.T) {
+	result := &struct {
+		wantStartAfter string
+		wantTotalFiles int64
+		wantSize       int64
+		wantPrefixes   int
+		wantLargest    string
+		wantCalls      int
+	}{
+		wantStartAfter: "team-a/2020/item1",
+		wantTotalFiles: 5,
+		wantSize:       1500,
+		wantPrefixes:   2,
+		wantLargest:    "item5",
+		wantCalls:      2,
+	}
+
+	calls := 0
+	s3c := s3client.S3Client{Api: S3AwsClientPagedMock{calls: &calls, failPage: 2}}
+	progress := s3client.NewProgress()
+
+	_, err := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{
+		BucketName: "bucket3", PrefixDepth: 2, TopN: 2, Progress: progress})
+	if err == nil {
+		t.Fatalf("Expecting error on the second page")
+	}
+
+	snapshot, ok := progress.Snapshot()
+	if !ok {
+		t.Fatalf("Expecting a snapshot of the listing")
+	}
+	if len(snapshot.Shards) != 1 || snapshot.Shards[0].StartAfter != result.wantStartAfter {
+		t.Fatalf("Expecting to resume after %v, got %+v", result.wantStartAfter, snapshot.Shards)
+	}
+
+	b, _ := json.Marshal(snapshot)
+	var resume s3client.ObjectStatsProgress
+	if err := json.Unmarshal(b, &resume); err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+
+	calls = 0
+	s3c = s3client.S3Client{Api: S3AwsClientPagedMock{calls: &calls}}
+	object, err := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{
+		BucketName: "bucket3", PrefixDepth: 2, TopN: 2, Resume: &resume})
+	if err != nil {
+		t.Fatalf("Expecting no error, got %v", err)
+	}
+	if calls != result.wantCalls {
+		t.Errorf("Expecting %v pages listed on resume, got %v", result.wantCalls, calls)
+	}
+	if object.TotalFiles != result.wantTotalFiles {
+		t.Errorf("Expecting %v, got %v", result.wantTotalFiles, object.TotalFiles)
+	}
+	if object.SizeInBytes != result.wantSize {
+		t.Errorf("Expecting %v, got %v", result.wantSize, object.SizeInBytes)
+	}
+	if len(object.Prefixes) != result.wantPrefixes || object.Prefixes[0].TotalFiles != 3 {
+		t.Errorf("Expecting %v prefixes, got %+v", result.wantPrefixes, object.Prefixes)
+	}
+	if object.LargestFiles[0].Key != result.wantLargest {
+		t.Errorf("Expecting %v, got %v", result.wantLargest, object.LargestFiles[0].Key)
+	}
+}
+
+func TestGetObjectStatsFilter(t *testing.T) {
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	d2 := time.Date(2020, time.April, 10, 22, 40, 20, 22, time.UTC)
+	for _, r := range []*struct {
+		in          s3client.ObjectStatsInput
+		totalFiles  int64
+		sizeInBytes int64
+	}{
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Prefixes: []string{"team-b/", "team-a/2020/"}}, 2, 500},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Prefix: "team-a/", Prefixes: []string{"team-a/2021/"}}, 3, 600},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Prefixes: []string{"team-a/", "team-b/"},
+			ShardMode: s3client.ShardPrefix, Limiter: s3client.NewSemaphore(2)}, 4, 1000},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Prefixes: []string{"team-a/", "item"},
+			ShardMode: s3client.ShardRange}, 4, 1100},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Filter: s3client.ObjectFilter{MinSize: 200, MaxSize: 400}}, 3, 900},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Filter: s3client.ObjectFilter{Suffixes: []string{"item1", "item5"}}}, 2, 600},
+		{s3client.ObjectStatsInput{BucketName: "bucket3", Filter: s3client.ObjectFilter{KeyPattern: "^team-a/20"},
+			ShardMode: s3client.ShardPrefix}, 2, 300},
+		{s3client.ObjectStatsInput{BucketName: "bucket1", Filter: s3client.ObjectFilter{StorageClasses: []string{"glacier"}}}, 1, 3232},
+		{s3client.ObjectStatsInput{BucketName: "bucket1", Filter: s3client.ObjectFilter{ModifiedAfter: d2}}, 1, 3232},
+		{s3client.ObjectStatsInput{BucketName: "bucket1", Filter: s3client.ObjectFilter{ModifiedBefore: d2}}, 1, 3131},
+	} {
+		object, err := s3c.GetObjectStats(context.TODO(), &r.in)
+		if err != nil {
+			t.Fatalf("Expecting no error, got %v", err)
+		}
+		if object.TotalFiles != r.totalFiles || object.SizeInBytes != r.sizeInBytes {
+			t.Errorf("Expecting %v files of %v bytes, got %v files of %v bytes",
+				r.totalFiles, r.sizeInBytes, object.TotalFiles, object.SizeInBytes)
+		}
+	}
+
+	_, err := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket3",
+		Filter: s3client.ObjectFilter{KeyPattern: "("}})
+	if err == nil {
+		t.Errorf("Expecting error for invalid key pattern")
+	}
+}
+
+func TestGetObjectStatsFutureObjects(t *testing.T) {
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	referenceDate := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	object, _ := s3c.GetObjectStats(context.TODO(), &s3client.ObjectStatsInput{BucketName: "bucket1", ReferenceDate: referenceDate})
+
+	var total int64
+	for _, ar := range object.AgeDistribution {
+		total += ar.TotalFiles
+	}
+	if total != object.TotalFiles {
+		t.Errorf("Expecting %v, got %v", object.TotalFiles, total)
+	}
+	if object.AgeDistribution[0].TotalFiles != 2 {
+		t.Errorf("Expecting %v, got %v", 2, object.AgeDistribution[0].TotalFiles)
+	}
+}
+
+func TestNewSizeDistribution(t *testing.T) {
+	result := &struct {
+		wantListSize   int
+		wantFirstLabel string
+		wantLastLabel  string
+		wantLastMin    int64
+	}{
+		wantListSize:   3,
+		wantFirstLabel: "< 1 KiB",
+		wantLastLabel:  ">= 1 MiB",
+		wantLastMin:    1 << 20,
+	}
+
+	sd := s3client.NewSizeDistribution([]int64{1 << 20, 1 << 10})
+
+	if len(sd) != result.wantListSize {
+		t.Fatalf("Expecting %v, got %v", result.wantListSize, len(sd))
+	}
+	if sd[0].Label != result.wantFirstLabel {
+		t.Errorf("Expecting %v, got %v", result.wantFirstLabel, sd[0].Label)
+	}
+	if sd[2].Label != result.wantLastLabel {
+		t.Errorf("Expecting %v, got %v", result.wantLastLabel, sd[2].Label)
+	}
+	if sd[2].MinSizeInBytes != result.wantLastMin {
+		t.Errorf("Expecting %v, got %v", result.wantLastMin, sd[2].MinSizeInBytes)
+	}
+}
+
+func TestGetObjectVersionStats(t *testing.T) {
+	result := &struct {
+		wantCurrentFiles         int64
+		wantCurrentSize          int64
+		wantNoncurrentFiles      int64
+		wantNoncurrentSize       int64
+		wantDeleteMarkers        int64
+		wantOldestNoncurrentFile string
+		wantOldestNoncurrentDate time.Time
+	}{
+		wantCurrentFiles:         1,
+		wantCurrentSize:          3131,
+		wantNoncurrentFiles:      2,
+		wantNoncurrentSize:       3000,
+		wantDeleteMarkers:        1,
+		wantOldestNoncurrentFile: "item1",
+		wantOldestNoncurrentDate: time.Date(2020, time.April, 10, 22, 40, 20, 11, time.UTC),
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetObjectVersionStats(context.TODO(), &s3client.ObjectVersionStatsInput{BucketName: "bucket1"})
+
+	if r.CurrentFiles != result.wantCurrentFiles {
+		t.Errorf("Expected %v, got %v", result.wantCurrentFiles, r.CurrentFiles)
+	}
+	if r.CurrentSizeInBytes != result.wantCurrentSize {
+		t.Errorf("Expected %v, got %v", result.wantCurrentSize, r.CurrentSizeInBytes)
+	}
+	if r.NoncurrentFiles != result.wantNoncurrentFiles {
+		t.Errorf("Expected %v, got %v", result.wantNoncurrentFiles, r.NoncurrentFiles)
+	}
+	if r.NoncurrentSizeInBytes != result.wantNoncurrentSize {
+		t.Errorf("Expected %v, got %v", result.wantNoncurrentSize, r.NoncurrentSizeInBytes)
+	}
+	if r.DeleteMarkers != result.wantDeleteMarkers {
+		t.Errorf("Expected %v, got %v", result.wantDeleteMarkers, r.DeleteMarkers)
+	}
+	if r.OldestNoncurrentFile != result.wantOldestNoncurrentFile {
+		t.Errorf("Expected %v, got %v", result.wantOldestNoncurrentFile, r.OldestNoncurrentFile)
+	}
+	if r.OldestNoncurrentFileModifiedDate != result.wantOldestNoncurrentDate {
+		t.Errorf("Expected %v, got %v", result.wantOldestNoncurrentDate, r.OldestNoncurrentFileModifiedDate)
+	}
+
+	r, _ = s3c.GetObjectVersionStats(context.TODO(), &s3client.ObjectVersionStatsInput{BucketName: "bucket1",
+		Filter: s3client.ObjectFilter{MinSize: 1500}})
+	if r.CurrentFiles != 1 || r.NoncurrentFiles != 1 || r.NoncurrentSizeInBytes != 2000 || r.DeleteMarkers != 1 {
+		t.Errorf("Expecting 1 current, 1 noncurrent of 2000 bytes and 1 delete marker, got %+v", r)
+	}
+
+	r, _ = s3c.GetObjectVersionStats(context.TODO(), &s3client.ObjectVersionStatsInput{BucketName: "bucket1",
+		Filter: s3client.ObjectFilter{Suffixes: []string{"item1"}}})
+	if r.CurrentFiles != 1 || r.NoncurrentFiles != 1 || r.NoncurrentSizeInBytes != 1000 || r.DeleteMarkers != 0 {
+		t.Errorf("Expecting 1 current, 1 noncurrent of 1000 bytes and no delete marker, got %+v", r)
+	}
+
+	_, err := s3c.GetObjectVersionStats(context.TODO(), &s3client.ObjectVersionStatsInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetObjectVersionStats(context.TODO(), &s3client.ObjectVersionStatsInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
+func TestGetMultipartUploadStats(t *testing.T) {
+	result := &struct {
+		wantTotalUploads int64
+		wantSize         int64
+		wantUploadSize   int64
+		wantUploadParts  int64
+		wantAbortRule    bool
+	}{
+		wantTotalUploads: 2,
+		wantSize:         12 << 20,
+		wantUploadSize:   6 << 20,
+		wantUploadParts:  2,
+		wantAbortRule:    true,
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetMultipartUploadStats(context.TODO(), &s3client.MultipartUploadStatsInput{BucketName: "bucket1"})
+
+	if r.TotalUploads != result.wantTotalUploads {
+		t.Errorf("Expected %v, got %v", result.wantTotalUploads, r.TotalUploads)
+	}
+	if r.SizeInBytes != result.wantSize {
+		t.Errorf("Expected %v, got %v", result.wantSize, r.SizeInBytes)
+	}
+	if len(r.Uploads) != int(result.wantTotalUploads) {
+		t.Fatalf("Expected %v, got %v", result.wantTotalUploads, len(r.Uploads))
+	}
+	if r.Uploads[0].SizeInBytes != result.wantUploadSize {
+		t.Errorf("Expected %v, got %v", result.wantUploadSize, r.Uploads[0].SizeInBytes)
+	}
+	if r.Uploads[0].TotalParts != result.wantUploadParts {
+		t.Errorf("Expected %v, got %v", result.wantUploadParts, r.Uploads[0].TotalParts)
+	}
+	if r.HasAbortIncompleteUploadsRule != result.wantAbortRule {
+		t.Errorf("Expected %v, got %v", result.wantAbortRule, r.HasAbortIncompleteUploadsRule)
+	}
+
+	for _, f := range []*struct {
+		filter           s3client.ObjectFilter
+		wantTotalUploads int64
+	}{
+		{s3client.ObjectFilter{Suffixes: []string{"item2"}}, 1},
+		{s3client.ObjectFilter{MaxSize: 1 << 20}, 0},
+		{s3client.ObjectFilter{StorageClasses: []string{"GLACIER"}}, 0},
+	} {
+		r, _ = s3c.GetMultipartUploadStats(context.TODO(), &s3client.MultipartUploadStatsInput{BucketName: "bucket1", Filter: f.filter})
+		if r.TotalUploads != f.wantTotalUploads || len(r.Uploads) != int(f.wantTotalUploads) {
+			t.Errorf("Expected %v, got %v", f.wantTotalUploads, r.TotalUploads)
+		}
+	}
+
+	_, err := s3c.GetMultipartUploadStats(context.TODO(), &s3client.MultipartUploadStatsInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetMultipartUploadStats(context.TODO(), &s3client.MultipartUploadStatsInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
 func TestGetBucketReplicationInfo(t *testing.T) {
 	result := &struct {
 		wantListSize int
@@ -178,6 +1101,11 @@ func TestGetBucketReplicationInfo(t *testing.T) {
 		wantStatus   types.ReplicationRuleStatus
 		wantID       string
 		wantPriority int32
+		wantRole     string
+		wantPrefix   string
+		wantRTC      string
+		wantRTCMin   int32
+		wantDMR      string
 	}{
 		wantListSize: 2,
 		wantDAccount: "123456789123",
@@ -186,6 +1114,11 @@ func TestGetBucketReplicationInfo(t *testing.T) {
 		wantStatus:   types.ReplicationRuleStatusEnabled,
 		wantID:       "id1",
 		wantPriority: 1,
+		wantRole:     "arn:aws:iam::123456789123:role/replication",
+		wantPrefix:   "dr/",
+		wantRTC:      "Enabled",
+		wantRTCMin:   15,
+		wantDMR:      "Enabled",
 	}
 	thisTime := time.Now()
 	t.Log("Starting Test: ", thisTime)
@@ -221,6 +1154,26 @@ func TestGetBucketReplicationInfo(t *testing.T) {
 		t.Errorf("Expected %v, got %v", result.wantStatus, r.ReplicationRules[0].Status)
 	}
 
+	if r.Role != result.wantRole {
+		t.Errorf("Expected %v, got %v", result.wantRole, r.Role)
+	}
+
+	if r.ReplicationRules[0].Filter.Prefix != result.wantPrefix {
+		t.Errorf("Expected %v, got %v", result.wantPrefix, r.ReplicationRules[0].Filter.Prefix)
+	}
+
+	if r.ReplicationRules[0].ReplicationTimeControl != result.wantRTC {
+		t.Errorf("Expected %v, got %v", result.wantRTC, r.ReplicationRules[0].ReplicationTimeControl)
+	}
+
+	if r.ReplicationRules[0].ReplicationTimeMinutes != result.wantRTCMin {
+		t.Errorf("Expected %v, got %v", result.wantRTCMin, r.ReplicationRules[0].ReplicationTimeMinutes)
+	}
+
+	if r.ReplicationRules[0].DeleteMarkerReplication != result.wantDMR {
+		t.Errorf("Expected %v, got %v", result.wantDMR, r.ReplicationRules[0].DeleteMarkerReplication)
+	}
+
 	_, err := s3c.GetBucketReplicationInfo(context.TODO(), &s3client.BucketReplicationInfoInput{BucketName: "bucket2"})
 	notFoundMsg := "Bucket Not Found"
 	if err.Error() != notFoundMsg {
@@ -235,15 +1188,182 @@ func TestGetBucketReplicationInfo(t *testing.T) {
 
 }
 
+func TestGetBucketVersioningInfo(t *testing.T) {
+	result := &struct {
+		wantStatus    string
+		wantMFADelete string
+	}{
+		wantStatus:    "Enabled",
+		wantMFADelete: "Disabled",
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetBucketVersioningInfo(context.TODO(), &s3client.BucketVersioningInfoInput{BucketName: "bucket1"})
+
+	if r.Status != result.wantStatus {
+		t.Errorf("Expected %v, got %v", result.wantStatus, r.Status)
+	}
+	if r.MFADelete != result.wantMFADelete {
+		t.Errorf("Expected %v, got %v", result.wantMFADelete, r.MFADelete)
+	}
+
+	_, err := s3c.GetBucketVersioningInfo(context.TODO(), &s3client.BucketVersioningInfoInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetBucketVersioningInfo(context.TODO(), &s3client.BucketVersioningInfoInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
+func TestGetBucketObjectLockInfo(t *testing.T) {
+	result := &struct {
+		wantEnabled bool
+		wantMode    string
+		wantDays    int32
+	}{
+		wantEnabled: true,
+		wantMode:    "COMPLIANCE",
+		wantDays:    30,
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetBucketObjectLockInfo(context.TODO(), &s3client.BucketObjectLockInfoInput{BucketName: "bucket1"})
+
+	if r.Enabled != result.wantEnabled {
+		t.Errorf("Expected %v, got %v", result.wantEnabled, r.Enabled)
+	}
+	if r.RetentionMode != result.wantMode {
+		t.Errorf("Expected %v, got %v", result.wantMode, r.RetentionMode)
+	}
+	if r.RetentionDays != result.wantDays {
+		t.Errorf("Expected %v, got %v", result.wantDays, r.RetentionDays)
+	}
+
+	_, err := s3c.GetBucketObjectLockInfo(context.TODO(), &s3client.BucketObjectLockInfoInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetBucketObjectLockInfo(context.TODO(), &s3client.BucketObjectLockInfoInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
+func TestGetBucketEncryptionInfo(t *testing.T) {
+	result := &struct {
+		wantAlgorithm string
+		wantKMSKey    string
+		wantBucketKey bool
+	}{
+		wantAlgorithm: "SSE-KMS",
+		wantKMSKey:    "arn:aws:kms:us-east-1:123456789123:key/key1",
+		wantBucketKey: true,
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetBucketEncryptionInfo(context.TODO(), &s3client.BucketEncryptionInfoInput{BucketName: "bucket1"})
+
+	if r.Algorithm != result.wantAlgorithm {
+		t.Errorf("Expected %v, got %v", result.wantAlgorithm, r.Algorithm)
+	}
+	if r.KMSMasterKeyID != result.wantKMSKey {
+		t.Errorf("Expected %v, got %v", result.wantKMSKey, r.KMSMasterKeyID)
+	}
+	if r.BucketKeyEnabled != result.wantBucketKey {
+		t.Errorf("Expected %v, got %v", result.wantBucketKey, r.BucketKeyEnabled)
+	}
+
+	_, err := s3c.GetBucketEncryptionInfo(context.TODO(), &s3client.BucketEncryptionInfoInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetBucketEncryptionInfo(context.TODO(), &s3client.BucketEncryptionInfoInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
+func TestGetBucketPublicAccessInfo(t *testing.T) {
+	result := &struct {
+		wantBlockedExposure  string
+		wantPublicExposure   string
+		wantPolicyStatements int
+		wantACLGrants        int
+	}{
+		wantBlockedExposure:  "blocked",
+		wantPublicExposure:   "policy-public",
+		wantPolicyStatements: 1,
+		wantACLGrants:        1,
+	}
+	api := S3AwsClientMock{}
+	s3c := s3client.S3Client{Api: api}
+
+	r, _ := s3c.GetBucketPublicAccessInfo(context.TODO(), &s3client.BucketPublicAccessInfoInput{BucketName: "bucket1"})
+	if r.Exposure != result.wantBlockedExposure {
+		t.Errorf("Expected %v, got %v", result.wantBlockedExposure, r.Exposure)
+	}
+
+	r, _ = s3c.GetBucketPublicAccessInfo(context.TODO(), &s3client.BucketPublicAccessInfoInput{BucketName: "bucket3"})
+	if r.Exposure != result.wantPublicExposure {
+		t.Errorf("Expected %v, got %v", result.wantPublicExposure, r.Exposure)
+	}
+	if len(r.PublicPolicyStatements) != result.wantPolicyStatements {
+		t.Errorf("Expected %v, got %v", result.wantPolicyStatements, len(r.PublicPolicyStatements))
+	}
+	if len(r.PublicACLGrants) != result.wantACLGrants {
+		t.Errorf("Expected %v, got %v", result.wantACLGrants, len(r.PublicACLGrants))
+	}
+
+	_, err := s3c.GetBucketPublicAccessInfo(context.TODO(), &s3client.BucketPublicAccessInfoInput{BucketName: "bucket2"})
+	notFoundMsg := "Bucket Not Found"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+
+	_, err = s3c.GetBucketPublicAccessInfo(context.TODO(), &s3client.BucketPublicAccessInfoInput{})
+	notFoundMsg = "Bucket name is required"
+	if err.Error() != notFoundMsg {
+		t.Errorf("Expected %v, got %v", notFoundMsg, err.Error())
+	}
+}
+
 func TestGetBucketLifecycleInfo(t *testing.T) {
 	result := &struct {
-		wantListSize int
-		wantStatus   types.ExpirationStatus
-		wantID       string
+		wantListSize        int
+		wantStatus          types.ExpirationStatus
+		wantID              string
+		wantPrefix          string
+		wantTagsSize        int
+		wantTransitionsSize int
+		wantTransitionClass string
+		wantExpirationDays  int32
+		wantNoncurrentDays  int32
+		wantAbortDays       int32
 	}{
-		wantListSize: 2,
-		wantStatus:   types.ExpirationStatusDisabled,
-		wantID:       "id1",
+		wantListSize:        2,
+		wantStatus:          types.ExpirationStatusDisabled,
+		wantID:              "id1",
+		wantPrefix:          "logs/",
+		wantTagsSize:        1,
+		wantTransitionsSize: 2,
+		wantTransitionClass: "GLACIER",
+		wantExpirationDays:  365,
+		wantNoncurrentDays:  30,
+		wantAbortDays:       7,
 	}
 	thisTime := time.Now()
 	t.Log("Starting Test: ", thisTime)
@@ -264,6 +1384,29 @@ func TestGetBucketLifecycleInfo(t *testing.T) {
 		t.Errorf("Expected %v, got %v", result.wantStatus, r.LifeCycleRules[1].Status)
 	}
 
+	lr := r.LifeCycleRules[0]
+	if !lr.Filter.And || lr.Filter.Prefix != result.wantPrefix {
+		t.Errorf("Expected And filter with prefix %v, got %+v", result.wantPrefix, lr.Filter)
+	}
+	if len(lr.Filter.Tags) != result.wantTagsSize {
+		t.Errorf("Expected %v, got %v", result.wantTagsSize, len(lr.Filter.Tags))
+	}
+	if len(lr.Transitions) != result.wantTransitionsSize {
+		t.Fatalf("Expected %v, got %v", result.wantTransitionsSize, len(lr.Transitions))
+	}
+	if lr.Transitions[1].StorageClass != result.wantTransitionClass {
+		t.Errorf("Expected %v, got %v", result.wantTransitionClass, lr.Transitions[1].StorageClass)
+	}
+	if lr.ExpirationDays != result.wantExpirationDays {
+		t.Errorf("Expected %v, got %v", result.wantExpirationDays, lr.ExpirationDays)
+	}
+	if lr.NoncurrentVersionExpirationDays != result.wantNoncurrentDays {
+		t.Errorf("Expected %v, got %v", result.wantNoncurrentDays, lr.NoncurrentVersionExpirationDays)
+	}
+	if lr.AbortIncompleteMultipartUploadDays != result.wantAbortDays {
+		t.Errorf("Expected %v, got %v", result.wantAbortDays, lr.AbortIncompleteMultipartUploadDays)
+	}
+
 	_, err := s3c.GetBucketLifecycleInfo(context.TODO(), &s3client.BucketLifeCycleInfoInput{BucketName: "bucket2"})
 	notFoundMsg := "Bucket Not Found"
 	if err.Error() != notFoundMsg {
@@ -277,3 +1420,45 @@ func TestGetBucketLifecycleInfo(t *testing.T) {
 	}
 
 }
+
+func TestNewS3Client(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(configFile, []byte("[profile stats]\nregion = sa-east-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{
+		"AWS_CONFIG_FILE":             configFile,
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(t.TempDir(), "credentials"),
+		"AWS_PROFILE":                 "",
+		"AWS_REGION":                  "",
+		"AWS_DEFAULT_REGION":          "",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	for _, r := range []*struct {
+		cc         s3client.ClientConfig
+		wantRegion string
+		wantErr    bool
+	}{
+		{s3client.ClientConfig{Region: "eu-west-1"}, "eu-west-1", false},
+		{s3client.ClientConfig{Profile: "stats"}, "sa-east-1", false},
+		{s3client.ClientConfig{Profile: "stats", Region: "eu-west-1", RoleARN: "arn:aws:iam::123456789012:role/stats",
+			ExternalID: "id", RoleSessionName: "stats"}, "eu-west-1", false},
+		{s3client.ClientConfig{Endpoint: "http://localhost:9000", UsePathStyle: true}, "us-east-1", false},
+		{s3client.ClientConfig{ExternalID: "id"}, "", true},
+		{s3client.ClientConfig{WebIdentityTokenFile: "token"}, "", true},
+		{s3client.ClientConfig{RoleARN: "arn:aws:iam::123456789012:role/stats", ExternalID: "id",
+			WebIdentityTokenFile: "token"}, "", true},
+		{s3client.ClientConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")}, "", true},
+	} {
+		s3c, err := s3client.NewS3Client(context.TODO(), r.cc)
+		if (err != nil) != r.wantErr {
+			t.Errorf("Expecting error %v for %+v, got %v", r.wantErr, r.cc, err)
+		}
+		if err == nil && s3c.Region != r.wantRegion {
+			t.Errorf("Expecting %v, got %v", r.wantRegion, s3c.Region)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package s3client
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ShardNone lists the whole bucket sequentially.
+	ShardNone = ""
+	// ShardPrefix splits the bucket by the prefixes found one delimiter level
+	// below the listed prefix.
+	ShardPrefix = "prefix"
+	// ShardRange splits the keyspace in ranges of keys using StartAfter.
+	ShardRange = "range"
+)
+
+// DefaultShardBoundaries splits the keyspace by the first character of the key.
+var DefaultShardBoundaries = strings.Split("0123456789abcdefghijklmnopqrstuvwxyz", "")
+
+// Semaphore bounds the number of listings in flight. A nil Semaphore has no free
+// slots, so shards are listed sequentially by the caller.
+type Semaphore chan struct{}
+
+func NewSemaphore(n int) Semaphore {
+	return make(Semaphore, n)
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+func (s Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes a slot only if one is free right away.
+func (s Semaphore) TryAcquire() bool {
+	if s == nil {
+		return false
+	}
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Semaphore) Release() {
+	<-s
+}
+
+// objectShard is a slice of the keyspace of a bucket. Keys after startAfter up
+// to and including endAt are listed, an empty endAt meaning no upper bound.
+type objectShard struct {
+	prefix     string
+	startAfter string
+	endAt      string
+}
+
+func (sh objectShard) includes(key string) bool {
+	return sh.endAt == "" || key <= sh.endAt
+}
+
+// rangeShards splits prefix in one shard before the first boundary, one between
+// each pair of boundaries and one after the last boundary.
+func rangeShards(prefix string, boundaries []string) []objectShard {
+	b := append([]string{}, boundaries...)
+	sort.Strings(b)
+
+	var shards []objectShard
+	startAfter := ""
+	for _, bound := range b {
+		endAt := prefix + bound
+		if bound == "" || endAt <= startAfter {
+			continue
+		}
+		shards = append(shards, objectShard{prefix: prefix, startAfter: startAfter, endAt: endAt})
+		startAfter = endAt
+	}
+
+	return append(shards, objectShard{prefix: prefix, startAfter: startAfter})
+}
+
+// prefixShards lists the level below prefix with the delimiter, adding the
+// objects found at that level that match m to bs and returning one shard per
+// common prefix.
+func (s3c S3Client) prefixShards(c context.Context, params *ObjectStatsInput, prefix string, bs *ObjectStatsOutput,
+	m *objectMatcher, optFns ...func(*s3.Options)) ([]objectShard, error) {
+	delimiter := params.Delimiter
+	if delimiter == "" {
+		delimiter = DefaultDelimiter
+	}
+
+	pg := s3.NewListObjectsV2Paginator(s3c.Api, &s3.ListObjectsV2Input{
+		Bucket:    &params.BucketName,
+		Prefix:    &prefix,
+		Delimiter: &delimiter,
+	})
+
+	var shards []objectShard
+	for pg.HasMorePages() {
+		loo, err := pg.NextPage(c, optFns...)
+		if err != nil {
+			log.Error("Error while listing prefixes: ", err)
+			return nil, err
+		}
+
+		for _, o := range loo.Contents {
+			if m.match(o) {
+				bs.addObject(o)
+			}
+		}
+		for _, cp := range loo.CommonPrefixes {
+			shards = append(shards, objectShard{prefix: *cp.Prefix})
+		}
+	}
+
+	return shards, nil
+}
+
+// listShard lists a shard from the last key listed, tracking each page in p.
+func (s3c S3Client) listShard(c context.Context, bucketName string, p *Progress, st *shardState,
+	m *objectMatcher, optFns ...func(*s3.Options)) error {
+	sh := st.shard
+	in := s3.ListObjectsV2Input{
+		Bucket: &bucketName,
+		Prefix: &sh.prefix,
+	}
+	if sh.startAfter != "" {
+		in.StartAfter = &sh.startAfter
+	}
+
+	pg := s3.NewListObjectsV2Paginator(s3c.Api, &in)
+	for pg.HasMorePages() {
+		loo, err := pg.NextPage(c, optFns...)
+		if err != nil {
+			log.Error("Error while listing objects: ", err)
+			return err
+		}
+
+		if p.addPage(st, loo.Contents, m) {
+			return nil
+		}
+	}
+
+	p.finish(st)
+	return nil
+}
+
+// listShards lists the pending shards of p. The caller lists shards itself and
+// spawns helpers only while params.Limiter has free slots, so the listings in
+// flight never exceed the limiter size.
+func (s3c S3Client) listShards(c context.Context, params *ObjectStatsInput, p *Progress,
+	m *objectMatcher, optFns ...func(*s3.Options)) error {
+	pending := p.pending()
+	if len(pending) > 1 {
+		log.Infof("Listing bucket %v in %v shards", params.BucketName, len(pending))
+	}
+
+	queue := make(chan *shardState, len(pending))
+	for _, st := range pending {
+		queue <- st
+	}
+	close(queue)
+
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	list := func(st *shardState) {
+		if err := s3c.listShard(ctx, params.BucketName, p, st, m, optFns...); err != nil {
+			lock.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			lock.Unlock()
+			cancel()
+		}
+	}
+
+	helper := func() {
+		defer wg.Done()
+		defer params.Limiter.Release()
+		for st := range queue {
+			if ctx.Err() != nil {
+				return
+			}
+			list(st)
+		}
+	}
+
+	for st := range queue {
+		for len(queue) > 0 && params.Limiter.TryAcquire() {
+			wg.Add(1)
+			go helper()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		list(st)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return c.Err()
+}
@@ -0,0 +1,109 @@
+package s3client
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type ObjectInfo struct {
+	Key          string
+	SizeInBytes  int64
+	StorageClass string
+	LastModified time.Time
+}
+
+// ranksBelow reports whether a ranks below b: it is smaller, or as large and
+// after b by key, so equal sizes rank the same whatever the listing order.
+func ranksBelow(a, b ObjectInfo) bool {
+	if a.SizeInBytes != b.SizeInBytes {
+		return a.SizeInBytes < b.SizeInBytes
+	}
+	return a.Key > b.Key
+}
+
+// objectHeap is a min-heap by rank, so the lowest ranked of the kept objects is
+// always at the root and can be evicted in O(log n).
+type objectHeap []ObjectInfo
+
+func (h objectHeap) Len() int            { return len(h) }
+func (h objectHeap) Less(i, j int) bool  { return ranksBelow(h[i], h[j]) }
+func (h objectHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *objectHeap) Push(x interface{}) { *h = append(*h, x.(ObjectInfo)) }
+func (h *objectHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+type topObjects struct {
+	n    int
+	heap objectHeap
+}
+
+func newTopObjects(n int) *topObjects {
+	return &topObjects{n: n}
+}
+
+func (t *topObjects) add(o types.Object) {
+	if len(t.heap) == t.n && o.Size < t.heap[0].SizeInBytes {
+		return
+	}
+
+	sc := string(o.StorageClass)
+	if sc == "" {
+		sc = string(types.ObjectStorageClassStandard)
+	}
+	t.push(ObjectInfo{
+		Key:          *o.Key,
+		SizeInBytes:  o.Size,
+		StorageClass: sc,
+		LastModified: *o.LastModified,
+	})
+}
+
+func (t *topObjects) merge(o *topObjects) {
+	for _, oi := range o.heap {
+		t.push(oi)
+	}
+}
+
+// push keeps oi if it ranks above the lowest ranked of the kept objects.
+func (t *topObjects) push(oi ObjectInfo) {
+	if len(t.heap) == t.n && !ranksBelow(t.heap[0], oi) {
+		return
+	}
+	heap.Push(&t.heap, oi)
+	if len(t.heap) > t.n {
+		heap.Pop(&t.heap)
+	}
+}
+
+// list returns the kept objects sorted from the largest to the smallest, and
+// by key when their sizes are equal.
+func (t *topObjects) list() []ObjectInfo {
+	r := append([]ObjectInfo{}, t.heap...)
+	sort.Slice(r, func(i, j int) bool { return ranksBelow(r[j], r[i]) })
+	return r
+}
+
+// largestPrefixes returns the n largest of the prefixes, sorted as list does.
+// Prefixes are ranked with the same bounded min-heap of the objects.
+func largestPrefixes(pss []PrefixStats, n int) []PrefixStats {
+	t := newTopObjects(n)
+	byPrefix := map[string]PrefixStats{}
+	for _, ps := range pss {
+		byPrefix[ps.Prefix] = ps
+		t.push(ObjectInfo{Key: ps.Prefix, SizeInBytes: ps.SizeInBytes})
+	}
+
+	var r []PrefixStats
+	for _, oi := range t.list() {
+		r = append(r, byPrefix[oi.Key])
+	}
+	return r
+}